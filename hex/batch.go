@@ -0,0 +1,115 @@
+package hex
+
+import (
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// ToLocs converts many world points to the locations of the hexagons
+// containing them. The results are stored in dst, which is grown if it is too
+// short, and returned. If workers > 1, the work is split among that many
+// goroutines.
+func (grid *HexGrid) ToLocs(dst []Loc, pts []mgl64.Vec2, workers int) []Loc {
+	dst = growLocs(dst, len(pts))
+	parallel(len(pts), workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			g := grid.toGridMat.Mul2x1(pts[i])
+			dst[i][0], dst[i][1] = AxialRoundInt(g[0], g[1])
+		}
+	})
+	return dst
+}
+
+// ToCenters converts many hexagon locations to the world coordinates of the
+// hexagons' centers. The results are stored in dst, which is grown if it is
+// too short, and returned. If workers > 1, the work is split among that many
+// goroutines.
+func (grid *HexGrid) ToCenters(dst []mgl64.Vec2, locs []Loc, workers int) []mgl64.Vec2 {
+	dst = growVecs(dst, len(locs))
+	parallel(len(locs), workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			dst[i] = grid.toWorldMat.Mul2x1(mgl64.Vec2{float64(locs[i][0]), float64(locs[i][1])})
+		}
+	})
+	return dst
+}
+
+// ToLocs converts many world points to the locations of the squares
+// containing them. The results are stored in dst, which is grown if it is too
+// short, and returned. If workers > 1, the work is split among that many
+// goroutines.
+func (grid *SquareGrid) ToLocs(dst []Loc, pts []mgl64.Vec2, workers int) []Loc {
+	dst = growLocs(dst, len(pts))
+	parallel(len(pts), workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			g := grid.toGridMat.Mul2x1(pts[i])
+			dst[i][0], dst[i][1] = grid.Tile(g[0], g[1])
+		}
+	})
+	return dst
+}
+
+// ToCenters converts many square locations to the world coordinates of the
+// squares' centers. The results are stored in dst, which is grown if it is
+// too short, and returned. If workers > 1, the work is split among that many
+// goroutines.
+func (grid *SquareGrid) ToCenters(dst []mgl64.Vec2, locs []Loc, workers int) []mgl64.Vec2 {
+	offset := 0.0
+	if grid.Anchor == CornerAnchor {
+		offset = 0.5
+	}
+	dst = growVecs(dst, len(locs))
+	parallel(len(locs), workers, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			dst[i] = grid.toWorldMat.Mul2x1(mgl64.Vec2{float64(locs[i][0]) + offset, float64(locs[i][1]) + offset})
+		}
+	})
+	return dst
+}
+
+// minimum number of items given to each goroutine by parallel().
+const minBatchChunk = 1024
+
+// parallel calls f on consecutive chunks of [0,n), using up to workers
+// goroutines. If workers <= 1, f is called once on the calling goroutine.
+func parallel(n, workers int, f func(lo, hi int)) {
+	if max := n / minBatchChunk; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		f(0, n)
+		return
+	}
+
+	chunk := (n + workers - 1) / workers
+	wg := sync.WaitGroup{}
+	for lo := 0; lo < n; lo += chunk {
+		hi := lo + chunk
+		if hi > n {
+			hi = n
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			f(lo, hi)
+			wg.Done()
+		}(lo, hi)
+	}
+	wg.Wait()
+}
+
+// growLocs returns a slice of length n, reusing s if it has enough capacity.
+func growLocs(s []Loc, n int) []Loc {
+	if cap(s) < n {
+		return make([]Loc, n)
+	}
+	return s[:n]
+}
+
+// growVecs returns a slice of length n, reusing s if it has enough capacity.
+func growVecs(s []mgl64.Vec2, n int) []mgl64.Vec2 {
+	if cap(s) < n {
+		return make([]mgl64.Vec2, n)
+	}
+	return s[:n]
+}
@@ -0,0 +1,156 @@
+package hex
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// batchGrid is the part of HexGrid and SquareGrid used by the batch tests.
+type batchGrid interface {
+	Topology
+	ToLocs(dst []Loc, pts []mgl64.Vec2, workers int) []Loc
+	ToCenters(dst []mgl64.Vec2, locs []Loc, workers int) []mgl64.Vec2
+}
+
+func randomPoints(n int) []mgl64.Vec2 {
+	rng := rand.New(rand.NewSource(1))
+	pts := make([]mgl64.Vec2, n)
+	for i := range pts {
+		pts[i] = mgl64.Vec2{rng.Float64()*2000 - 1000, rng.Float64()*2000 - 1000}
+	}
+	return pts
+}
+
+func TestBatch(t *testing.T) {
+	grids := map[string]batchGrid{
+		"hex flat":   NewHexGrid(3, FlatTop),
+		"hex pointy": NewHexGrid(3, PointyTop),
+		"square":     NewSquareGrid(3, 0.3),
+		"square corner": func() *SquareGrid {
+			g := NewSquareGrid(3, 0.3)
+			g.Anchor = CornerAnchor
+			return g
+		}(),
+	}
+	pts := randomPoints(10000)
+
+	for name, grid := range grids {
+		t.Run(name, func(t *testing.T) {
+			for _, workers := range []int{1, 4} {
+				locs := grid.ToLocs(nil, pts, workers)
+				centers := grid.ToCenters(nil, locs, workers)
+				for i, p := range pts {
+					c, r := grid.Tile(grid.ToGrid(p.Elem()))
+					if locs[i] != (Loc{c, r}) {
+						t.Fatalf("ToLocs() [%d] = %v, want %v", i, locs[i], Loc{c, r})
+					}
+					x, y := grid.Center(c, r)
+					if !centers[i].ApproxEqualThreshold(mgl64.Vec2{x, y}, epsilon) {
+						t.Fatalf("ToCenters() [%d] = %v, want %v", i, centers[i], mgl64.Vec2{x, y})
+					}
+				}
+			}
+		})
+	}
+}
+
+const benchPoints = 1000000
+
+// BenchmarkHexGrid_PerPointInverse is the baseline for the batch
+// conversions: ToGrid as it was before the inverse matrix was cached,
+// inverting it for every point.
+func BenchmarkHexGrid_PerPointInverse(b *testing.B) {
+	grid := NewHexGrid(3, PointyTop)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, p := range pts {
+			g := grid.toWorldMat.Inv().Mul2x1(p)
+			locs[i][0], locs[i][1] = grid.Tile(g.Elem())
+		}
+	}
+}
+
+func BenchmarkHexGrid_PerPoint(b *testing.B) {
+	grid := NewHexGrid(3, PointyTop)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, p := range pts {
+			locs[i][0], locs[i][1] = grid.Tile(grid.ToGrid(p.Elem()))
+		}
+	}
+}
+
+func BenchmarkHexGrid_ToLocs(b *testing.B) {
+	grid := NewHexGrid(3, PointyTop)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		locs = grid.ToLocs(locs, pts, 1)
+	}
+}
+
+func BenchmarkHexGrid_ToLocsParallel(b *testing.B) {
+	grid := NewHexGrid(3, PointyTop)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		locs = grid.ToLocs(locs, pts, runtime.GOMAXPROCS(0))
+	}
+}
+
+func BenchmarkHexGrid_ToCenters(b *testing.B) {
+	grid := NewHexGrid(3, PointyTop)
+	locs := grid.ToLocs(nil, randomPoints(benchPoints), 1)
+	centers := make([]mgl64.Vec2, len(locs))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		centers = grid.ToCenters(centers, locs, 1)
+	}
+}
+
+// BenchmarkSquareGrid_PerPointInverse is the baseline for the batch
+// conversions: ToGrid as it was before the inverse matrix was cached,
+// inverting it for every point.
+func BenchmarkSquareGrid_PerPointInverse(b *testing.B) {
+	grid := NewSquareGrid(3, 0.3)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, p := range pts {
+			g := grid.toWorldMat.Inv().Mul2x1(p)
+			locs[i][0], locs[i][1] = grid.Tile(g.Elem())
+		}
+	}
+}
+
+func BenchmarkSquareGrid_PerPoint(b *testing.B) {
+	grid := NewSquareGrid(3, 0.3)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, p := range pts {
+			locs[i][0], locs[i][1] = grid.Tile(grid.ToGrid(p.Elem()))
+		}
+	}
+}
+
+func BenchmarkSquareGrid_ToLocs(b *testing.B) {
+	grid := NewSquareGrid(3, 0.3)
+	pts := randomPoints(benchPoints)
+	locs := make([]Loc, len(pts))
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		locs = grid.ToLocs(locs, pts, 1)
+	}
+}
@@ -0,0 +1,186 @@
+package hex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Patch is the set of changes that turns the data of one grid into that of
+// another. Use Diff to make one and Apply to replay it.
+type Patch struct {
+	Added    map[Loc]interface{} // tiles with data only in the new grid
+	Removed  []Loc               // tiles with data only in the old grid
+	Modified map[Loc]interface{} // tiles whose data changed, with the new data
+}
+
+// Diff finds the changes that turn the data in grid a into the data in grid
+// b. Data values are compared with reflect.DeepEqual.
+func Diff(a, b Grid) *Patch {
+	p := &Patch{
+		Added:    make(map[Loc]interface{}),
+		Modified: make(map[Loc]interface{}),
+	}
+	am, bm := a.Map(), b.Map()
+	for k, bv := range bm {
+		av, ok := am[k]
+		switch {
+		case !ok:
+			p.Added[k] = bv
+		case !reflect.DeepEqual(av, bv):
+			p.Modified[k] = bv
+		}
+	}
+	for k := range am {
+		if _, ok := bm[k]; !ok {
+			p.Removed = append(p.Removed, k)
+		}
+	}
+	sortLocs(p.Removed)
+	return p
+}
+
+// Apply makes the changes in the patch to grid.
+func Apply(grid Grid, p *Patch) {
+	for _, k := range p.Removed {
+		grid.Set(k[0], k[1], nil)
+	}
+	for k, v := range p.Added {
+		grid.Set(k[0], k[1], v)
+	}
+	for k, v := range p.Modified {
+		grid.Set(k[0], k[1], v)
+	}
+}
+
+// Len is the total number of changes in the patch.
+func (p *Patch) Len() int {
+	return len(p.Added) + len(p.Removed) + len(p.Modified)
+}
+
+// identifies and versions the binary encoding of a Patch.
+var patchMagic = [4]byte{'H', 'X', 'P', 1}
+
+// MarshalBinary encodes the patch compactly. Locations are written as
+// varints, and the data values with encoding/gob, so any data types other
+// than the basic ones must be registered with gob.Register.
+func (p *Patch) MarshalBinary() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.Write(patchMagic[:])
+
+	added, modified := sortedKeys(p.Added), sortedKeys(p.Modified)
+	writeUvarint(buf, uint64(len(added)))
+	writeUvarint(buf, uint64(len(p.Removed)))
+	writeUvarint(buf, uint64(len(modified)))
+
+	values := make([]interface{}, 0, len(added)+len(modified))
+	for _, k := range added {
+		writeLoc(buf, k)
+		values = append(values, p.Added[k])
+	}
+	for _, k := range p.Removed {
+		writeLoc(buf, k)
+	}
+	for _, k := range modified {
+		writeLoc(buf, k)
+		values = append(values, p.Modified[k])
+	}
+
+	if len(values) > 0 {
+		if err := gob.NewEncoder(buf).Encode(values); err != nil {
+			return nil, fmt.Errorf("encoding patch values: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a patch encoded with MarshalBinary, replacing the
+// current contents of p.
+func (p *Patch) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || magic != patchMagic {
+		return errors.New("decoding patch: not a patch or unsupported version")
+	}
+
+	var counts [3]uint64
+	for i := range counts {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return fmt.Errorf("decoding patch: %w", err)
+		}
+		if n > uint64(r.Len()) { // every loc takes at least 2 bytes
+			return errors.New("decoding patch: count too large")
+		}
+		counts[i] = n
+	}
+
+	locs := make([][]Loc, 3)
+	for i, n := range counts {
+		locs[i] = make([]Loc, n)
+		for j := range locs[i] {
+			k, err := readLoc(r)
+			if err != nil {
+				return fmt.Errorf("decoding patch: %w", err)
+			}
+			locs[i][j] = k
+		}
+	}
+
+	var values []interface{}
+	if counts[0]+counts[2] > 0 {
+		if err := gob.NewDecoder(r).Decode(&values); err != nil {
+			return fmt.Errorf("decoding patch values: %w", err)
+		}
+	}
+	if uint64(len(values)) != counts[0]+counts[2] {
+		return errors.New("decoding patch: wrong number of values")
+	}
+
+	p.Added = make(map[Loc]interface{}, counts[0])
+	p.Removed = locs[1]
+	p.Modified = make(map[Loc]interface{}, counts[2])
+	for i, k := range locs[0] {
+		p.Added[k] = values[i]
+	}
+	for i, k := range locs[2] {
+		p.Modified[k] = values[int(counts[0])+i]
+	}
+	return nil
+}
+
+// sortedKeys gets the keys of m sorted by (c,r).
+func sortedKeys(m map[Loc]interface{}) []Loc {
+	locs := make([]Loc, 0, len(m))
+	for k := range m {
+		locs = append(locs, k)
+	}
+	sortLocs(locs)
+	return locs
+}
+
+func writeUvarint(buf *bytes.Buffer, x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], x)])
+}
+
+func writeLoc(buf *bytes.Buffer, k Loc) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], int64(k[0]))])
+	buf.Write(b[:binary.PutVarint(b[:], int64(k[1]))])
+}
+
+func readLoc(r io.ByteReader) (k Loc, err error) {
+	for i := range k {
+		x, err := binary.ReadVarint(r)
+		if err != nil {
+			return k, err
+		}
+		k[i] = int(x)
+	}
+	return k, nil
+}
@@ -0,0 +1,95 @@
+package hex
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// fillRandom puts random data at random tiles of grid.
+func fillRandom(grid Grid, rng *rand.Rand, n int) {
+	for i := 0; i < n; i++ {
+		c, r := rng.Intn(20)-10, rng.Intn(20)-10
+		switch rng.Intn(3) {
+		case 0:
+			grid.Set(c, r, rng.Intn(3))
+		case 1:
+			grid.Set(c, r, "s")
+		case 2:
+			grid.Set(c, r, rng.Float64())
+		}
+	}
+}
+
+func TestDiffApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		newGrid func() Grid
+	}{
+		{"hex", func() Grid { return NewHexGrid(1, PointyTop) }},
+		{"square", func() Grid { return NewSquareGrid(1, 0) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			for i := 0; i < 50; i++ {
+				a, b := tt.newGrid(), tt.newGrid()
+				fillRandom(a, rng, 100)
+				fillRandom(b, rng, 100)
+
+				patch := Diff(a, b)
+				data, err := patch.MarshalBinary()
+				if err != nil {
+					t.Fatal(err)
+				}
+				decoded := &Patch{}
+				if err := decoded.UnmarshalBinary(data); err != nil {
+					t.Fatal(err)
+				}
+
+				Apply(a, decoded)
+				if !reflect.DeepEqual(a.Map(), b.Map()) {
+					t.Fatalf("Apply(a, Diff(a, b)) != b")
+				}
+				if Diff(a, b).Len() != 0 {
+					t.Fatalf("Diff() of equal grids is not empty")
+				}
+			}
+		})
+	}
+}
+
+func TestPatch_UnmarshalBinary(t *testing.T) {
+	a, b := NewHexGrid(1, FlatTop), NewHexGrid(1, FlatTop)
+	a.Set(0, 0, 1)
+	a.Set(1, 0, 1)
+	b.Set(0, 0, 2)
+	b.Set(-5, 3, 3)
+	patch := Diff(a, b)
+	want := &Patch{
+		Added:    map[Loc]interface{}{{-5, 3}: 3},
+		Removed:  []Loc{{1, 0}},
+		Modified: map[Loc]interface{}{{0, 0}: 2},
+	}
+	if !reflect.DeepEqual(patch, want) {
+		t.Fatalf("Diff() = %v, want %v", patch, want)
+	}
+
+	data, _ := patch.MarshalBinary()
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("XXXX"), data[4:]...)},
+		{"truncated", data[:len(data)-3]},
+		{"huge count", append(append([]byte{}, data[:4]...), 0xff, 0xff, 0xff, 0x0f)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&Patch{}).UnmarshalBinary(tt.data); err == nil {
+				t.Error("UnmarshalBinary() did not fail")
+			}
+		})
+	}
+}
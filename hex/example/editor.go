@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"fun/hex"
+	"math"
+	"os"
+	"sort"
+)
+
+// brush is a way of painting tiles in the editor.
+type brush int
+
+// the editor's brushes
+const (
+	singleBrush  brush = iota // paints the tile under the cursor
+	hexagonBrush              // paints all tiles within radius of the cursor
+	fillBrush                 // paints the connected tiles with the same value as the one clicked
+	lineBrush                 // paints a line between 2 clicked tiles
+)
+
+var brushNames = [...]string{"single", "hexagon", "fill", "line"}
+
+func (b brush) String() string { return brushNames[b] }
+
+// palette of hues which can be painted. An eraser is also available.
+var palette = []float64{0, 30, 60, 120, 180, 220, 270, 320}
+
+// editor holds the state of the map editor.
+type editor struct {
+	grid      hex.Topology
+	gridType  string
+	size      float64 // size of the grid's tiles, kept for saving
+	brush     brush
+	radius    int
+	value     interface{} // current value to paint. nil erases.
+	lineStart *hex.Loc    // first end of a line, if one has been clicked
+}
+
+// newEditor creates an editor for grid, which is of type gridType and has
+// tiles of the given size.
+func newEditor(grid hex.Topology, gridType string, size float64) *editor {
+	return &editor{
+		grid:     grid,
+		gridType: gridType,
+		size:     size,
+		brush:    singleBrush,
+		radius:   1,
+		value:    palette[0],
+	}
+}
+
+// status describes the editor's current settings.
+func (ed *editor) status() string {
+	value := "eraser"
+	if ed.value != nil {
+		value = fmt.Sprintf("hue %.0f", ed.value.(float64))
+	}
+	s := fmt.Sprintf("brush: %s | value: %s", ed.brush, value)
+	if ed.brush == hexagonBrush {
+		s += fmt.Sprintf(" | radius: %d", ed.radius)
+	}
+	if ed.brush == lineBrush && ed.lineStart != nil {
+		s += fmt.Sprintf(" | line from %v", *ed.lineStart)
+	}
+	return s
+}
+
+// paint applies the current brush at loc.
+func (ed *editor) paint(loc hex.Loc) {
+	var locs []hex.Loc
+	switch ed.brush {
+	case singleBrush:
+		locs = []hex.Loc{loc}
+	case hexagonBrush:
+		locs = hex.Within(ed.grid, loc, ed.radius)
+	case fillBrush:
+		locs = ed.fillRegion(loc)
+	case lineBrush:
+		if ed.lineStart == nil {
+			ed.lineStart = &loc
+			return
+		}
+		locs = hex.Line(ed.grid, *ed.lineStart, loc)
+		ed.lineStart = nil
+	}
+
+	for _, l := range locs {
+		c, r := l.CR()
+		ed.grid.Set(c, r, ed.value)
+	}
+}
+
+// fillRegion finds the tiles connected to start having the same value as
+// start. If start is empty, only start is returned, since the empty part of
+// the grid is unbounded.
+func (ed *editor) fillRegion(start hex.Loc) []hex.Loc {
+	target, ok := ed.grid.Get(start.CR())
+	if !ok {
+		return []hex.Loc{start}
+	}
+	return hex.FloodFill(ed.grid, start, func(l hex.Loc, data interface{}) bool {
+		return data == target
+	})
+}
+
+// mapFile is the format in which grids are saved.
+type mapFile struct {
+	Type  string    `json:"type"`
+	Size  float64   `json:"size"`
+	Tiles []mapTile `json:"tiles"`
+}
+
+// mapTile is a single tile in a mapFile.
+type mapTile struct {
+	C int     `json:"c"`
+	R int     `json:"r"`
+	V float64 `json:"v"`
+}
+
+// newGrid creates an empty grid of the given type ("h" or "s") and size.
+func newGrid(gridType string, size float64) (hex.Topology, error) {
+	switch gridType {
+	case "h":
+		return hex.NewHexGrid(size, hex.PointyTop), nil
+	case "s":
+		return hex.NewSquareGrid(size, math.Pi/4), nil // grid squares rotated 45 deg
+	default:
+		return nil, fmt.Errorf("invalid grid type: %s", gridType)
+	}
+}
+
+// saveGrid writes grid to a file.
+func saveGrid(filename, gridType string, size float64, grid hex.Grid) error {
+	m := mapFile{Type: gridType, Size: size}
+	for k, v := range grid.Map() {
+		m.Tiles = append(m.Tiles, mapTile{C: k[0], R: k[1], V: v.(float64)})
+	}
+	// sorted so that saving the same map twice gives the same file
+	sort.Slice(m.Tiles, func(i, j int) bool {
+		if m.Tiles[i].C != m.Tiles[j].C {
+			return m.Tiles[i].C < m.Tiles[j].C
+		}
+		return m.Tiles[i].R < m.Tiles[j].R
+	})
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// loadGrid reads a grid written by saveGrid, returning the grid, its type and
+// its size.
+func loadGrid(filename string) (hex.Topology, string, float64, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	var m mapFile
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, "", 0, fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	grid, err := newGrid(m.Type, m.Size)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	for _, t := range m.Tiles {
+		grid.Set(t.C, t.R, t.V)
+	}
+	return grid, m.Type, m.Size, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fun/hex"
 	"math"
 	"math/rand"
+	"os"
 	"time"
 
 	"golang.org/x/image/colornames"
@@ -20,15 +21,59 @@ import (
 	"github.com/faiface/pixel/pixelgl"
 )
 
+// keys used to choose palette values. the eraser is on key 0.
+var paletteKeys = []pixelgl.Button{
+	pixelgl.Key1, pixelgl.Key2, pixelgl.Key3, pixelgl.Key4,
+	pixelgl.Key5, pixelgl.Key6, pixelgl.Key7, pixelgl.Key8,
+}
+
+// keys used to choose brushes, in the same order as the brush constants.
+var brushKeys = []pixelgl.Button{pixelgl.KeyQ, pixelgl.KeyW, pixelgl.KeyE, pixelgl.KeyR}
+
 func run() {
 	rand.Seed(time.Now().UnixNano())
 
 	// command line flag
 	gridType := flag.String("type", "h", "Type of grid (h = hex, s = square).")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-type h|s] [mapfile]\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), `
+controls:
+  right mouse    paint with the current brush
+  q w e r        brush: single, hexagon, fill, line
+  - =            shrink/grow the hexagon brush
+  1-8, 0         palette value, eraser
+  s, l           save to/load from mapfile (default "map.json")
+`)
+	}
 	flag.Parse()
 
+	const hexRadius = 40
+	filename := "map.json"
+	var grid hex.Topology
+	size := float64(hexRadius)
+	if flag.NArg() > 0 {
+		filename = flag.Arg(0)
+	}
+	if _, err := os.Stat(filename); flag.NArg() > 0 && err == nil {
+		grid, *gridType, size, err = loadGrid(filename)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		grid, err = newGrid(*gridType, hexRadius)
+		if err != nil {
+			fmt.Printf("%s. Defaulting to hex ('h').\n", err)
+			*gridType = "h"
+			grid, _ = newGrid(*gridType, hexRadius)
+		}
+	}
+	ed := newEditor(grid, *gridType, size)
+
 	cfg := pixelgl.WindowConfig{
-		Title:  "Grid example",
+		Title:  "Grid editor",
 		Bounds: pixel.R(0, 0, 800, 800),
 		VSync:  true,
 	}
@@ -36,56 +81,86 @@ func run() {
 	if err != nil {
 		panic(err)
 	}
-
-	const hexRadius = 40
-	var grid hex.Grid
-	switch *gridType {
-	case "h":
-		grid = hex.NewHexGrid(hexRadius, hex.PointyTop)
-	case "s":
-		grid = hex.NewSquareGrid(hexRadius, math.Pi/4) // grid squares rotated 45 deg
-	default:
-		fmt.Printf("Invalid grid type: %s. Defaulting to hex ('h').\n", *gridType)
-		grid = hex.NewHexGrid(hexRadius, hex.PointyTop)
-	}
 	imd := imdraw.New(nil)
 
-	// create some initial data
-	// pts, min, max := genHexPoints(5)
-	// for _, p := range pts {
-	// 	grid.Set(p.C(), p.R(), 0.0)
-	// }
-	// fmt.Printf("min and max grid coordinates: %d, %d\n", min, max)
-
 	// func to draw the hex tiles with data
 	render := func() {
 		imd.Reset()
-		for k, v := range grid.Map() {
+		for k, v := range ed.grid.Map() {
 			imd.Color = colorful.Hsv(v.(float64), 1, 1)
-			for _, vert := range grid.Vertices(k.CR()) {
+			for _, vert := range ed.grid.Vertices(k.CR()) {
 				imd.Push(pixel.V(vert.X(), vert.Y()))
 			}
 			imd.Polygon(0)
 		}
+		win.SetTitle(fmt.Sprintf("Grid editor - %s - %s", filename, ed.status()))
 	}
 	render() // do once
 
 	cam := pxu.NewMouseCamera(win.Bounds().Center())
 
 	for !win.Closed() {
+		changed := false
 
-		if win.JustPressed(pixelgl.MouseButtonRight) {
-			click := cam.Unproject(win.MousePosition()) // convert mouse position to screen/world position
-			x, y := grid.ToGrid(click.XY())             // convert world position to grid coords
-			c, r := grid.Tile(x, y)                     // find grid tile's position (col,row) from fractional grid coords
-			loc := hex.Loc{c, r}
-			fmt.Printf("raw grid: %0.2f, %0.2f\tloc: %v\n", x, y, loc)
-			v, ok := grid.Get(c, r)
-			if ok {
-				grid.Set(c, r, math.Mod(v.(float64)+10, 360))
+		for i, key := range brushKeys {
+			if win.JustPressed(key) {
+				ed.brush = brush(i)
+				ed.lineStart = nil
+				changed = true
+			}
+		}
+		for i, key := range paletteKeys {
+			if win.JustPressed(key) {
+				ed.value = palette[i]
+				changed = true
+			}
+		}
+		if win.JustPressed(pixelgl.Key0) {
+			ed.value = nil
+			changed = true
+		}
+		if win.JustPressed(pixelgl.KeyEqual) {
+			ed.radius++
+			changed = true
+		}
+		if win.JustPressed(pixelgl.KeyMinus) && ed.radius > 0 {
+			ed.radius--
+			changed = true
+		}
+
+		if win.JustPressed(pixelgl.KeyS) {
+			if err := saveGrid(filename, ed.gridType, ed.size, ed.grid); err != nil {
+				fmt.Println("save failed:", err)
+			} else {
+				fmt.Println("saved", filename)
+			}
+		}
+		if win.JustPressed(pixelgl.KeyL) {
+			if g, t, size, err := loadGrid(filename); err != nil {
+				fmt.Println("load failed:", err)
 			} else {
-				grid.Set(c, r, 0.0)
+				ed.grid, ed.gridType, ed.size = g, t, size
+				ed.lineStart = nil
+				changed = true
+				fmt.Println("loaded", filename)
 			}
+		}
+
+		// single and hexagon brushes paint while the button is held. the others
+		// act once per click.
+		paint := win.JustPressed(pixelgl.MouseButtonRight)
+		if ed.brush == singleBrush || ed.brush == hexagonBrush {
+			paint = win.Pressed(pixelgl.MouseButtonRight)
+		}
+		if paint {
+			click := cam.Unproject(win.MousePosition()) // convert mouse position to screen/world position
+			x, y := ed.grid.ToGrid(click.XY())          // convert world position to grid coords
+			c, r := ed.grid.Tile(x, y)                  // find grid tile's position (col,row) from fractional grid coords
+			ed.paint(hex.Loc{c, r})
+			changed = true
+		}
+
+		if changed {
 			render()
 		}
 
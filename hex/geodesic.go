@@ -0,0 +1,278 @@
+package hex
+
+import (
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// CellID identifies a cell of a GeodesicGrid.
+type CellID int
+
+// GeodesicGrid is a grid of (mostly) hexagonal cells covering the unit sphere,
+// made by subdividing the faces of an icosahedron. This is the dual of a
+// geodesic polyhedron, also called a Goldberg polyhedron. Exactly 12 of the
+// cells, those with IDs 0 to 11, are pentagons; the rest are hexagons.
+//
+// Arbitrary user data can be associated with a particular cell by using the
+// 'Data' map, as with HexGrid.
+type GeodesicGrid struct {
+	Frequency int // number of segments each icosahedron edge is divided into
+	Data      map[CellID]interface{}
+	centers   []mgl64.Vec3
+	neighbors [][]CellID
+	corners   [][]mgl64.Vec3
+}
+
+// vertices of a regular icosahedron (before normalizing).
+var icosahedronVertices = func() []mgl64.Vec3 {
+	phi := (1 + math.Sqrt(5)) / 2
+	return []mgl64.Vec3{
+		{-1, phi, 0}, {1, phi, 0}, {-1, -phi, 0}, {1, -phi, 0},
+		{0, -1, phi}, {0, 1, phi}, {0, -1, -phi}, {0, 1, -phi},
+		{phi, 0, -1}, {phi, 0, 1}, {-phi, 0, -1}, {-phi, 0, 1},
+	}
+}()
+
+// faces of the icosahedron as indices into icosahedronVertices, wound
+// counter-clockwise when viewed from outside.
+var icosahedronFaces = [20][3]int{
+	{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+	{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+	{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+	{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+}
+
+// NewGeodesicGrid creates a grid on the unit sphere by dividing each edge of
+// an icosahedron into frequency segments. The grid has 10*frequency^2 + 2
+// cells.
+func NewGeodesicGrid(frequency int) *GeodesicGrid {
+	if frequency < 1 {
+		panic("frequency must be at least 1")
+	}
+	grid := &GeodesicGrid{
+		Frequency: frequency,
+		Data:      make(map[CellID]interface{}),
+	}
+
+	// Subdivide each face into a triangular lattice. Points are keyed by their
+	// integer-weighted (unnormalized) position, which is computed exactly the
+	// same way on both faces sharing an edge, so shared points are found.
+	index := make(map[mgl64.Vec3]CellID)
+	addPoint := func(key, p mgl64.Vec3) CellID {
+		if id, ok := index[key]; ok {
+			return id
+		}
+		id := CellID(len(grid.centers))
+		index[key] = id
+		grid.centers = append(grid.centers, p)
+		return id
+	}
+	for _, v := range icosahedronVertices {
+		// so that the pentagons are cells 0-11
+		addPoint(v.Mul(float64(frequency)), v.Normalize())
+	}
+
+	var triangles [][3]CellID
+	for _, f := range icosahedronFaces {
+		a, b, c := icosahedronVertices[f[0]], icosahedronVertices[f[1]], icosahedronVertices[f[2]]
+		point := func(i, j int) CellID {
+			wa, wb, wc := float64(frequency-i-j), float64(i), float64(j)
+			key := a.Mul(wa).Add(b.Mul(wb)).Add(c.Mul(wc))
+			return addPoint(key, facePoint(a, b, c, frequency, i, j))
+		}
+		for i := 0; i < frequency; i++ {
+			for j := 0; i+j < frequency; j++ {
+				triangles = append(triangles, [3]CellID{point(i, j), point(i+1, j), point(i, j+1)})
+				if i+j < frequency-1 {
+					triangles = append(triangles, [3]CellID{point(i+1, j), point(i+1, j+1), point(i, j+1)})
+				}
+			}
+		}
+	}
+
+	// Each lattice point is the center of a cell whose corners are the centers
+	// of the triangles around the point.
+	cells := len(grid.centers)
+	grid.neighbors = make([][]CellID, cells)
+	grid.corners = make([][]mgl64.Vec3, cells)
+	for _, t := range triangles {
+		center := grid.centers[t[0]].Add(grid.centers[t[1]]).Add(grid.centers[t[2]]).Normalize()
+		for k, id := range t {
+			grid.corners[id] = append(grid.corners[id], center)
+			grid.neighbors[id] = appendUnique(grid.neighbors[id], t[(k+1)%3])
+		}
+	}
+	for id := range grid.centers {
+		grid.sortAround(CellID(id))
+	}
+
+	return grid
+}
+
+// facePoint gets the position on the unit sphere of lattice point (i,j) of the
+// spherical triangle abc divided into n segments per edge, where i counts
+// towards b and j counts towards c.
+//
+// Simply normalizing the flat lattice points bunches cells up near the
+// icosahedron's vertices, so instead the point is placed by dividing great
+// circle arcs into equal parts: along two edges to get the ends of the point's
+// "row", then along the row. Doing this from each of the 3 corners and
+// averaging the results keeps the construction symmetric, and makes the cell
+// areas nearly uniform.
+func facePoint(a, b, c mgl64.Vec3, n, i, j int) mgl64.Vec3 {
+	a, b, c = a.Normalize(), b.Normalize(), c.Normalize()
+	row := func(a, b, c mgl64.Vec3, i, j int) mgl64.Vec3 {
+		if j == n {
+			return c
+		}
+		t := float64(j) / float64(n)
+		return slerp(slerp(a, c, t), slerp(b, c, t), float64(i)/float64(n-j))
+	}
+	k := n - i - j
+	return row(a, b, c, i, j).Add(row(b, c, a, j, k)).Add(row(c, a, b, k, i)).Normalize()
+}
+
+// slerp spherically interpolates between the unit vectors a and b.
+func slerp(a, b mgl64.Vec3, t float64) mgl64.Vec3 {
+	omega := math.Acos(mgl64.Clamp(a.Dot(b), -1, 1))
+	if omega < 1e-12 {
+		return a
+	}
+	sin := math.Sin(omega)
+	return a.Mul(math.Sin((1-t)*omega) / sin).Add(b.Mul(math.Sin(t*omega) / sin))
+}
+
+// sortAround orders the corners and neighbors of a cell counter-clockwise
+// around its center, as viewed from outside the sphere.
+func (grid *GeodesicGrid) sortAround(id CellID) {
+	center := grid.centers[id]
+	// basis for the plane tangent to the sphere at the cell's center
+	u := center.Cross(mgl64.Vec3{0, 0, 1})
+	if u.Len() < 1e-9 {
+		u = center.Cross(mgl64.Vec3{1, 0, 0})
+	}
+	u = u.Normalize()
+	v := center.Cross(u)
+	angle := func(p mgl64.Vec3) float64 {
+		return math.Atan2(p.Dot(v), p.Dot(u))
+	}
+
+	corners := grid.corners[id]
+	sort.Slice(corners, func(i, j int) bool { return angle(corners[i]) < angle(corners[j]) })
+	nbs := grid.neighbors[id]
+	sort.Slice(nbs, func(i, j int) bool {
+		return angle(grid.centers[nbs[i]]) < angle(grid.centers[nbs[j]])
+	})
+}
+
+// Len gets the number of cells in the grid.
+func (grid *GeodesicGrid) Len() int {
+	return len(grid.centers)
+}
+
+// IsPentagon says if the cell is one of the 12 pentagons.
+func (grid *GeodesicGrid) IsPentagon(id CellID) bool {
+	return len(grid.neighbors[id]) == 5
+}
+
+// Center gets the center of the cell as a point on the unit sphere.
+func (grid *GeodesicGrid) Center(id CellID) mgl64.Vec3 {
+	return grid.centers[id]
+}
+
+// Vertices gets the 5 or 6 corners of the cell as points on the unit sphere,
+// going counter-clockwise as viewed from outside the sphere.
+func (grid *GeodesicGrid) Vertices(id CellID) []mgl64.Vec3 {
+	return grid.corners[id]
+}
+
+// Neighbors gets the IDs of the 5 or 6 cells sharing an edge with the cell,
+// going counter-clockwise as viewed from outside the sphere.
+func (grid *GeodesicGrid) Neighbors(id CellID) []CellID {
+	return grid.neighbors[id]
+}
+
+// LatLon gets the latitude and longitude, in degrees, of the cell's center.
+func (grid *GeodesicGrid) LatLon(id CellID) (lat, lon float64) {
+	return ToLatLon(grid.centers[id])
+}
+
+// Cell gets the cell containing the point at the given latitude and
+// longitude, in degrees. This is the cell whose center is closest to the
+// point.
+func (grid *GeodesicGrid) Cell(lat, lon float64) CellID {
+	return grid.CellAt(FromLatLon(lat, lon))
+}
+
+// CellAt gets the cell containing the point on the sphere in the direction
+// of p, which need not be normalized.
+func (grid *GeodesicGrid) CellAt(p mgl64.Vec3) CellID {
+	p = p.Normalize()
+
+	// start from the closest pentagon, then walk towards p until no neighbor's
+	// center is closer.
+	best, bestDot := CellID(0), math.Inf(-1)
+	for id := CellID(0); id < 12; id++ {
+		if d := grid.centers[id].Dot(p); d > bestDot {
+			best, bestDot = id, d
+		}
+	}
+	for moved := true; moved; {
+		moved = false
+		for _, nb := range grid.neighbors[best] {
+			if d := grid.centers[nb].Dot(p); d > bestDot {
+				best, bestDot, moved = nb, d, true
+			}
+		}
+	}
+	return best
+}
+
+// Get returns the data at the cell and a boolean indicating whether or not
+// data existed there.
+func (grid *GeodesicGrid) Get(id CellID) (data interface{}, ok bool) {
+	data, ok = grid.Data[id]
+	return
+}
+
+// Set sets the data at the cell. If data is nil, the value at the cell is
+// deleted.
+func (grid *GeodesicGrid) Set(id CellID, data interface{}) {
+	grid.Data[id] = data
+	if data == nil {
+		delete(grid.Data, id)
+	}
+}
+
+// Map gets access to the grid's data, for use in "range", etc.
+func (grid *GeodesicGrid) Map() map[CellID]interface{} {
+	return grid.Data
+}
+
+// ToLatLon converts a point on the unit sphere to latitude and longitude in
+// degrees.
+func ToLatLon(p mgl64.Vec3) (lat, lon float64) {
+	lat = math.Asin(mgl64.Clamp(p.Z(), -1, 1))
+	lon = math.Atan2(p.Y(), p.X())
+	return mgl64.RadToDeg(lat), mgl64.RadToDeg(lon)
+}
+
+// FromLatLon converts latitude and longitude in degrees to a point on the unit
+// sphere.
+func FromLatLon(lat, lon float64) mgl64.Vec3 {
+	sinLat, cosLat := math.Sincos(mgl64.DegToRad(lat))
+	sinLon, cosLon := math.Sincos(mgl64.DegToRad(lon))
+	return mgl64.Vec3{cosLat * cosLon, cosLat * sinLon, sinLat}
+}
+
+// appendUnique appends id to ids if it is not already there.
+func appendUnique(ids []CellID, id CellID) []CellID {
+	for _, x := range ids {
+		if x == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
@@ -0,0 +1,127 @@
+package hex
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestNewGeodesicGrid(t *testing.T) {
+	for _, freq := range []int{1, 2, 5, 16} {
+		grid := NewGeodesicGrid(freq)
+		if want := 10*freq*freq + 2; grid.Len() != want {
+			t.Errorf("frequency %d: Len() = %d, want %d", freq, grid.Len(), want)
+		}
+
+		for id := CellID(0); id < CellID(grid.Len()); id++ {
+			nbs := grid.Neighbors(id)
+			if grid.IsPentagon(id) != (id < 12) {
+				t.Fatalf("frequency %d: cell %d has %d neighbors", freq, id, len(nbs))
+			}
+			if len(grid.Vertices(id)) != len(nbs) {
+				t.Fatalf("frequency %d: cell %d has %d vertices and %d neighbors",
+					freq, id, len(grid.Vertices(id)), len(nbs))
+			}
+			for _, nb := range nbs {
+				if !containsCell(grid.Neighbors(nb), id) {
+					t.Fatalf("frequency %d: %d is a neighbor of %d but not vice versa", freq, nb, id)
+				}
+			}
+
+			// corners go counter-clockwise when viewed from outside
+			center, verts := grid.Center(id), grid.Vertices(id)
+			for i := range verts {
+				a, b := verts[i].Sub(center), verts[(i+1)%len(verts)].Sub(center)
+				if a.Cross(b).Dot(center) <= 0 {
+					t.Fatalf("frequency %d: cell %d vertices are not counter-clockwise", freq, id)
+				}
+			}
+		}
+	}
+}
+
+func TestGeodesicGrid_Cell(t *testing.T) {
+	grid := NewGeodesicGrid(12)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		lat := mgl64.RadToDeg(math.Asin(rng.Float64()*2 - 1))
+		lon := rng.Float64()*360 - 180
+		p := FromLatLon(lat, lon)
+
+		// brute force: the cell with the nearest center
+		want, wantDot := CellID(0), math.Inf(-1)
+		for id := CellID(0); id < CellID(grid.Len()); id++ {
+			if d := grid.Center(id).Dot(p); d > wantDot {
+				want, wantDot = id, d
+			}
+		}
+		if got := grid.Cell(lat, lon); got != want {
+			t.Fatalf("Cell(%v, %v) = %d, want %d", lat, lon, got, want)
+		}
+	}
+
+	for id := CellID(0); id < CellID(grid.Len()); id++ {
+		if got := grid.Cell(grid.LatLon(id)); got != id {
+			t.Fatalf("Cell(LatLon(%d)) = %d", id, got)
+		}
+	}
+}
+
+func TestGeodesicGrid_Storage(t *testing.T) {
+	grid := NewGeodesicGrid(2)
+	grid.Set(7, "x")
+	if v, ok := grid.Get(7); !ok || v != "x" {
+		t.Errorf("Get(7) = %v, %v, want x, true", v, ok)
+	}
+	grid.Set(7, nil)
+	if len(grid.Map()) != 0 {
+		t.Errorf("Set(7, nil) did not delete: %v", grid.Map())
+	}
+}
+
+// TestGeodesicGrid_Area checks that the cells are all close to the same size.
+func TestGeodesicGrid_Area(t *testing.T) {
+	for _, freq := range []int{4, 10, 24} {
+		grid := NewGeodesicGrid(freq)
+		total, min, max := 0.0, math.Inf(1), math.Inf(-1)
+		for id := CellID(0); id < CellID(grid.Len()); id++ {
+			a := sphericalPolygonArea(grid.Center(id), grid.Vertices(id))
+			total += a
+			if !grid.IsPentagon(id) {
+				min, max = math.Min(min, a), math.Max(max, a)
+			}
+		}
+
+		if math.Abs(total-4*math.Pi) > 1e-9 {
+			t.Errorf("frequency %d: cells cover %v of the sphere, want 4*Pi", freq, total)
+		}
+		if ratio := max / min; ratio > 1.15 {
+			t.Errorf("frequency %d: largest hexagon is %.3f times the smallest", freq, ratio)
+		}
+	}
+}
+
+// sphericalPolygonArea gets the area of the polygon on the unit sphere with
+// the given vertices by summing the triangles fanning out from center.
+func sphericalPolygonArea(center mgl64.Vec3, verts []mgl64.Vec3) (area float64) {
+	for i := range verts {
+		a, b := verts[i], verts[(i+1)%len(verts)]
+		// Van Oosterom and Strackee's formula for a spherical triangle
+		num := math.Abs(center.Dot(a.Cross(b)))
+		den := 1 + center.Dot(a) + a.Dot(b) + b.Dot(center)
+		area += 2 * math.Atan2(num, den)
+	}
+	return
+}
+
+func containsCell(ids []CellID, id CellID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
@@ -43,6 +43,7 @@ type HexGrid struct {
 	Orientation  HexagonOrientation
 	Data         map[Loc]interface{}
 	toWorldMat   mgl64.Mat2
+	toGridMat    mgl64.Mat2 // inverse of toWorldMat
 }
 
 // NewHexGrid creates the data structure to represent a hexagonal grid in
@@ -67,6 +68,7 @@ func NewHexGrid(circumradius float64, orientation HexagonOrientation) *HexGrid {
 	default:
 		panic("incorrect orientation")
 	}
+	grid.toGridMat = grid.toWorldMat.Inv()
 
 	return grid
 }
@@ -79,7 +81,7 @@ func (grid *HexGrid) ToWorld(c, r float64) (float64, float64) {
 
 // ToGrid converts world coordinates to axial grid coordinates.
 func (grid *HexGrid) ToGrid(x, y float64) (float64, float64) {
-	g := grid.toWorldMat.Inv().Mul2x1(mgl64.Vec2{x, y})
+	g := grid.toGridMat.Mul2x1(mgl64.Vec2{x, y})
 	return g.X(), g.Y()
 }
 
@@ -94,7 +96,7 @@ func (grid *HexGrid) Vertices(c, r int) (verts []mgl64.Vec2) {
 		offset = math.Pi / 6 // 30 deg
 	}
 
-	x, y := grid.ToWorld(float64(c), float64(r))
+	x, y := grid.Center(c, r)
 	for i := 0.0; i < 6; i++ {
 		theta := i*math.Pi/3 + offset
 		sin, cos := math.Sincos(theta)
@@ -105,6 +107,11 @@ func (grid *HexGrid) Vertices(c, r int) (verts []mgl64.Vec2) {
 	return
 }
 
+// Center gets the world coordinates of the center of the hexagon at (c,r).
+func (grid *HexGrid) Center(c, r int) (float64, float64) {
+	return grid.ToWorld(float64(c), float64(r))
+}
+
 // Get returns the data at axial coordinates (c,r) and a boolean indicating
 // whether or not data existed at that location. Really it's just a convenience
 // method for accessing the Data member.
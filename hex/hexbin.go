@@ -0,0 +1,133 @@
+package hex
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Stat selects one of the statistics kept for each bin of a Hexbin.
+type Stat int
+
+// the statistics kept for each bin
+const (
+	StatCount Stat = iota
+	StatSum
+	StatMean
+	StatMin
+	StatMax
+)
+
+// BinStats are the statistics of the weighted points that fell into one bin.
+type BinStats struct {
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+// Mean is the average weight of the points in the bin.
+func (s *BinStats) Mean() float64 {
+	return s.Sum / float64(s.Count)
+}
+
+// Value gets the requested statistic.
+func (s *BinStats) Value(stat Stat) float64 {
+	switch stat {
+	case StatCount:
+		return float64(s.Count)
+	case StatSum:
+		return s.Sum
+	case StatMean:
+		return s.Mean()
+	case StatMin:
+		return s.Min
+	case StatMax:
+		return s.Max
+	default:
+		panic("incorrect stat")
+	}
+}
+
+// add accumulates a point with the given weight.
+func (s *BinStats) add(weight float64) {
+	if s.Count == 0 {
+		s.Min, s.Max = weight, weight
+	}
+	s.Count++
+	s.Sum += weight
+	s.Min = math.Min(s.Min, weight)
+	s.Max = math.Max(s.Max, weight)
+}
+
+// Hexbin accumulates weighted (x,y) points into the hexagons of a HexGrid, in
+// the manner of a "hexbin" chart. Each hexagon holding points has a *BinStats
+// as its data in Grid.
+type Hexbin struct {
+	Grid *HexGrid
+	locs []Loc // reused by AddPoints
+}
+
+// NewHexbin creates an empty Hexbin whose bins are hexagons of the given size
+// and orientation.
+func NewHexbin(circumradius float64, orientation HexagonOrientation) *Hexbin {
+	return &Hexbin{Grid: NewHexGrid(circumradius, orientation)}
+}
+
+// Add puts a point at world coordinates (x,y) with the given weight into the
+// bin containing it.
+func (h *Hexbin) Add(x, y, weight float64) {
+	c, r := h.Grid.Tile(h.Grid.ToGrid(x, y))
+	h.bin(Loc{c, r}).add(weight)
+}
+
+// AddPoints adds many points at once. If weights is nil, every point has a
+// weight of 1; otherwise it must be as long as pts.
+func (h *Hexbin) AddPoints(pts []mgl64.Vec2, weights []float64) {
+	if weights != nil && len(weights) != len(pts) {
+		panic("hexbin: len(weights) != len(pts)")
+	}
+
+	h.locs = h.Grid.ToLocs(h.locs, pts, 1)
+	for i, l := range h.locs {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		h.bin(l).add(w)
+	}
+}
+
+// Get gets the statistics of the bin at (c,r), or nil if the bin is empty.
+func (h *Hexbin) Get(c, r int) *BinStats {
+	if data, ok := h.Grid.Get(c, r); ok {
+		return data.(*BinStats)
+	}
+	return nil
+}
+
+// Range gets the smallest and largest value of stat over all non-empty bins.
+// If there are no bins, both are 0.
+func (h *Hexbin) Range(stat Stat) (min, max float64) {
+	first := true
+	for _, data := range h.Grid.Map() {
+		v := data.(*BinStats).Value(stat)
+		if first {
+			min, max = v, v
+			first = false
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	return
+}
+
+// bin gets the stats for the bin at l, creating them if needed.
+func (h *Hexbin) bin(l Loc) *BinStats {
+	if data, ok := h.Grid.Data[l]; ok {
+		return data.(*BinStats)
+	}
+	s := &BinStats{}
+	h.Grid.Data[l] = s
+	return s
+}
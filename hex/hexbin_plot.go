@@ -0,0 +1,256 @@
+package hex
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Colormap maps a value in [0,1] to a color.
+type Colormap func(t float64) color.Color
+
+// Grayscale is a Colormap from black to white.
+func Grayscale(t float64) color.Color {
+	v := uint8(math.Round(clamp01(t) * 255))
+	return color.RGBA{v, v, v, 255}
+}
+
+// stops of matplotlib's "viridis" colormap, evenly spaced in [0,1].
+var viridisStops = []color.RGBA{
+	{68, 1, 84, 255},
+	{72, 40, 120, 255},
+	{62, 74, 137, 255},
+	{49, 104, 142, 255},
+	{38, 130, 142, 255},
+	{31, 158, 137, 255},
+	{53, 183, 121, 255},
+	{110, 206, 88, 255},
+	{181, 222, 43, 255},
+	{253, 231, 37, 255},
+}
+
+// Viridis is a Colormap approximating matplotlib's default "viridis".
+func Viridis(t float64) color.Color {
+	t = clamp01(t) * float64(len(viridisStops)-1)
+	i := int(t)
+	if i == len(viridisStops)-1 {
+		return viridisStops[i]
+	}
+	a, b, f := viridisStops[i], viridisStops[i+1], t-float64(i)
+	mix := func(x, y uint8) uint8 { return uint8(math.Round(lerp(float64(x), float64(y), f))) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// PlotOptions control how a Hexbin is drawn.
+type PlotOptions struct {
+	Width, Height int         // size of the whole plot in pixels, including legend
+	Stat          Stat        // statistic used to color the bins
+	Colormap      Colormap    // defaults to Viridis
+	Background    color.Color // defaults to white
+	Legend        bool        // draw a color bar showing the range of Stat
+}
+
+// sizes in pixels of parts of the legend.
+const (
+	legendWidth  = 80 // including labels
+	legendMargin = 10
+	legendBar    = 16
+	legendText   = 13 // height of a line of text
+)
+
+// plot holds the values needed while drawing a Hexbin.
+type plot struct {
+	opts     PlotOptions
+	min, max float64
+	// world to pixel transform: px = (x-x0)*scale + offX, py = (y0-y)*scale + offY
+	x0, y0, scale, offX, offY float64
+}
+
+// newPlot fills in default options and fits the bins into the plot area.
+func (h *Hexbin) newPlot(opts PlotOptions) *plot {
+	if opts.Colormap == nil {
+		opts.Colormap = Viridis
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+	p := &plot{opts: opts}
+	p.min, p.max = h.Range(opts.Stat)
+
+	// world bounds of all bin vertices
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for k := range h.Grid.Map() {
+		for _, v := range h.Grid.Vertices(k.CR()) {
+			minX, maxX = math.Min(minX, v.X()), math.Max(maxX, v.X())
+			minY, maxY = math.Min(minY, v.Y()), math.Max(maxY, v.Y())
+		}
+	}
+	if math.IsInf(minX, 1) {
+		minX, minY, maxX, maxY = 0, 0, 1, 1
+	}
+
+	// fit to area, keeping the aspect ratio, and center
+	areaW := float64(p.areaWidth())
+	areaH := float64(opts.Height)
+	p.scale = math.Min(areaW/(maxX-minX), areaH/(maxY-minY))
+	p.x0, p.y0 = minX, maxY
+	p.offX = (areaW - (maxX-minX)*p.scale) / 2
+	p.offY = (areaH - (maxY-minY)*p.scale) / 2
+	return p
+}
+
+// areaWidth is the width of the part of the plot where bins are drawn.
+func (p *plot) areaWidth() int {
+	if p.opts.Legend {
+		return p.opts.Width - legendWidth
+	}
+	return p.opts.Width
+}
+
+// toPixel converts world coordinates to (fractional) pixel coordinates.
+func (p *plot) toPixel(x, y float64) (float64, float64) {
+	return (x-p.x0)*p.scale + p.offX, (p.y0-y)*p.scale + p.offY
+}
+
+// toWorld converts pixel coordinates to world coordinates.
+func (p *plot) toWorld(px, py float64) (float64, float64) {
+	return (px-p.offX)/p.scale + p.x0, p.y0 - (py-p.offY)/p.scale
+}
+
+// color gets the color for a bin.
+func (p *plot) color(s *BinStats) color.Color {
+	t := 0.0
+	if p.max > p.min {
+		t = (s.Value(p.opts.Stat) - p.min) / (p.max - p.min)
+	}
+	return p.opts.Colormap(t)
+}
+
+// Image draws the hexbin chart.
+func (h *Hexbin) Image(opts PlotOptions) *image.RGBA {
+	p := h.newPlot(opts)
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(p.opts.Background), image.Point{}, draw.Src)
+
+	// color each pixel by the bin containing its center
+	for py := 0; py < opts.Height; py++ {
+		for px := 0; px < p.areaWidth(); px++ {
+			x, y := p.toWorld(float64(px)+0.5, float64(py)+0.5)
+			if s := h.Get(h.Grid.Tile(h.Grid.ToGrid(x, y))); s != nil {
+				img.Set(px, py, p.color(s))
+			}
+		}
+	}
+
+	if opts.Legend {
+		p.drawLegend(img)
+	}
+	return img
+}
+
+// legendBounds gets the left edge and the vertical extent of the legend's
+// color bar.
+func (p *plot) legendBounds() (left, top, bottom int) {
+	left = p.areaWidth() + legendMargin
+	top = legendMargin + legendText
+	bottom = p.opts.Height - legendMargin - legendText
+	return
+}
+
+// drawLegend draws a vertical color bar with the min and max values.
+func (p *plot) drawLegend(img *image.RGBA) {
+	left, top, bottom := p.legendBounds()
+	for y := top; y < bottom; y++ {
+		c := p.opts.Colormap(float64(bottom-1-y) / float64(bottom-1-top))
+		for x := left; x < left+legendBar; x++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: basicfont.Face7x13,
+	}
+	d.Dot = fixed.P(left, top-3)
+	d.DrawString(formatValue(p.max))
+	d.Dot = fixed.P(left, bottom+11)
+	d.DrawString(formatValue(p.min))
+}
+
+// WritePNG draws the hexbin chart and writes it to w as a PNG.
+func (h *Hexbin) WritePNG(w io.Writer, opts PlotOptions) error {
+	return png.Encode(w, h.Image(opts))
+}
+
+// WriteSVG draws the hexbin chart and writes it to w as an SVG.
+func (h *Hexbin) WriteSVG(w io.Writer, opts PlotOptions) error {
+	p := h.newPlot(opts)
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(p.opts.Background))
+
+	locs := make([]Loc, 0, len(h.Grid.Data))
+	for k := range h.Grid.Data {
+		locs = append(locs, k)
+	}
+	sortLocs(locs) // stable output
+	for _, k := range locs {
+		fmt.Fprint(bw, `<polygon points="`)
+		for i, v := range h.Grid.Vertices(k.CR()) {
+			if i > 0 {
+				fmt.Fprint(bw, " ")
+			}
+			x, y := p.toPixel(v.X(), v.Y())
+			fmt.Fprintf(bw, "%.2f,%.2f", x, y)
+		}
+		fmt.Fprintf(bw, `" fill="%s"/>`+"\n", svgColor(p.color(h.Get(k.CR()))))
+	}
+
+	if opts.Legend {
+		const stops = 10
+		left, top, bottom := p.legendBounds()
+		fmt.Fprint(bw, `<defs><linearGradient id="legend" x1="0" y1="1" x2="0" y2="0">`)
+		for i := 0; i <= stops; i++ {
+			t := float64(i) / stops
+			fmt.Fprintf(bw, `<stop offset="%g" stop-color="%s"/>`, t, svgColor(p.opts.Colormap(t)))
+		}
+		fmt.Fprint(bw, "</linearGradient></defs>\n")
+		fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="url(#legend)"/>`+"\n",
+			left, top, legendBar, bottom-top)
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-family="monospace" font-size="12">%s</text>`+"\n",
+			left, top-3, formatValue(p.max))
+		fmt.Fprintf(bw, `<text x="%d" y="%d" font-family="monospace" font-size="12">%s</text>`+"\n",
+			left, bottom+11, formatValue(p.min))
+	}
+
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// svgColor formats c as an SVG color.
+func svgColor(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+}
+
+// formatValue formats a legend value compactly.
+func formatValue(v float64) string {
+	return fmt.Sprintf("%.4g", v)
+}
+
+func clamp01(t float64) float64 {
+	return math.Max(0, math.Min(1, t))
+}
@@ -0,0 +1,88 @@
+package hex
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+func TestHexbin_Add(t *testing.T) {
+	h := NewHexbin(1, PointyTop)
+	h.Add(0, 0, 2)
+	h.Add(0.1, -0.1, 4)
+	h.Add(0.2, 0.3, 9)
+	h.Add(10, 10, 1)
+
+	s := h.Get(0, 0)
+	if s == nil {
+		t.Fatal("Get(0, 0) = nil")
+	}
+	tests := []struct {
+		stat Stat
+		want float64
+	}{
+		{StatCount, 3},
+		{StatSum, 15},
+		{StatMean, 5},
+		{StatMin, 2},
+		{StatMax, 9},
+	}
+	for _, tt := range tests {
+		if got := s.Value(tt.stat); math.Abs(got-tt.want) > epsilon {
+			t.Errorf("Value(%d) = %v, want %v", tt.stat, got, tt.want)
+		}
+	}
+
+	if min, max := h.Range(StatCount); min != 1 || max != 3 {
+		t.Errorf("Range() = %v, %v, want 1, 3", min, max)
+	}
+}
+
+func TestHexbin_AddPoints(t *testing.T) {
+	pts := randomPoints(1000)
+	a, b := NewHexbin(50, FlatTop), NewHexbin(50, FlatTop)
+	for _, p := range pts {
+		a.Add(p.X(), p.Y(), 1)
+	}
+	b.AddPoints(pts, nil)
+
+	if len(a.Grid.Data) != len(b.Grid.Data) {
+		t.Fatalf("AddPoints() made %d bins, want %d", len(b.Grid.Data), len(a.Grid.Data))
+	}
+	for k, v := range a.Grid.Data {
+		if *b.Get(k.CR()) != *v.(*BinStats) {
+			t.Errorf("AddPoints() bin %v = %v, want %v", k, b.Get(k.CR()), v)
+		}
+	}
+}
+
+func TestHexbin_Plot(t *testing.T) {
+	h := NewHexbin(1, PointyTop)
+	h.AddPoints([]mgl64.Vec2{{0, 0}, {0, 0}, {5, 0}}, nil)
+	opts := PlotOptions{Width: 200, Height: 100, Stat: StatCount, Colormap: Grayscale, Legend: true}
+
+	img := h.Image(opts)
+	if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 100 {
+		t.Errorf("Image() size = %v", img.Bounds())
+	}
+	// the legend's bar runs from the max color at the top to the min at the bottom
+	left, top, bottom := (&plot{opts: opts}).legendBounds()
+	if got := img.At(left, top); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("legend top = %v, want white", got)
+	}
+	if got := img.At(left, bottom-1); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("legend bottom = %v, want black", got)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.WriteSVG(buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "<polygon"); n != 2 {
+		t.Errorf("WriteSVG() drew %d bins, want 2", n)
+	}
+}
@@ -0,0 +1,246 @@
+package hex
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Palette maps the colors of an image to grid data values, for ReadImage and
+// WriteImage. Values which are not comparable (such as slices) are matched with
+// reflect.DeepEqual. A color mapped to nil marks empty tiles. Colors with an
+// alpha of less than 255 are compared premultiplied.
+type Palette map[color.RGBA]interface{}
+
+// UnknownColorError is returned by ReadImage when some pixels have colors that
+// are not in the palette.
+type UnknownColorError struct {
+	Pixels []image.Point // the offending pixels
+	Colors []color.RGBA  // the color of each offending pixel
+}
+
+// maximum number of pixels listed in an UnknownColorError's message.
+const maxListedPixels = 10
+
+func (e *UnknownColorError) Error() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%d pixels have colors not in the palette:", len(e.Pixels))
+	for i, p := range e.Pixels {
+		if i == maxListedPixels {
+			fmt.Fprintf(b, " and %d more", len(e.Pixels)-maxListedPixels)
+			break
+		}
+		c := e.Colors[i]
+		fmt.Fprintf(b, " (%d,%d)=#%02x%02x%02x%02x", p.X, p.Y, c.R, c.G, c.B, c.A)
+	}
+	return b.String()
+}
+
+// OffsetToAxial converts "offset" coordinates, in which hexagons are laid out
+// in plain columns and rows, to axial coordinates. For PointyTop grids odd
+// rows are shifted half a hexagon right; for FlatTop grids odd columns are
+// shifted half a hexagon up.
+func (grid *HexGrid) OffsetToAxial(col, row int) Loc {
+	if grid.Orientation == FlatTop {
+		return Loc{col, row - col>>1}
+	}
+	return Loc{col - row>>1, row}
+}
+
+// AxialToOffset converts axial coordinates to offset coordinates. It is the
+// inverse of OffsetToAxial.
+func (grid *HexGrid) AxialToOffset(l Loc) (col, row int) {
+	if grid.Orientation == FlatTop {
+		return l[0], l[1] + l[0]>>1
+	}
+	return l[0] + l[1]>>1, l[1]
+}
+
+// offsetLayout gets the functions converting between grid locations and
+// offset (col,row) coordinates for the grids which can be stored as images.
+func offsetLayout(grid Grid) (toLoc func(col, row int) Loc, toOffset func(Loc) (int, int), err error) {
+	switch g := grid.(type) {
+	case *SquareGrid:
+		toLoc = func(col, row int) Loc { return Loc{col, row} }
+		toOffset = func(l Loc) (int, int) { return l[0], l[1] }
+	case *HexGrid:
+		toLoc = g.OffsetToAxial
+		toOffset = g.AxialToOffset
+	default:
+		err = fmt.Errorf("grids of type %T cannot be stored as images", grid)
+	}
+	return
+}
+
+// ReadImage sets the data of grid, which must be a *SquareGrid or *HexGrid,
+// from img with one pixel per tile. Each pixel's color is mapped to a value
+// through palette. The bottom left pixel of the image is the tile at offset
+// (0,0), and offsets increase to the right and up (see
+// SquareGrid.PointToLoc and HexGrid.OffsetToAxial).
+//
+// If any pixel's color is not in the palette, an *UnknownColorError listing
+// those pixels is returned and grid is not changed.
+func ReadImage(grid Grid, img image.Image, palette Palette) error {
+	toLoc, _, err := offsetLayout(grid)
+	if err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	data := make(map[Loc]interface{}, b.Dx()*b.Dy())
+	unknown := &UnknownColorError{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			v, ok := palette[c]
+			switch {
+			case !ok:
+				unknown.Pixels = append(unknown.Pixels, image.Point{x, y})
+				unknown.Colors = append(unknown.Colors, c)
+			case v != nil:
+				off := pointToOffset(image.Point{x, y}, b)
+				data[toLoc(off[0], off[1])] = v
+			}
+		}
+	}
+	if len(unknown.Pixels) > 0 {
+		return unknown
+	}
+
+	for k, v := range data {
+		grid.Set(k[0], k[1], v)
+	}
+	return nil
+}
+
+// WriteImage draws the data of grid, which must be a *SquareGrid or *HexGrid,
+// to an image with one pixel per tile, using the same layout as ReadImage.
+// Each value is mapped to a color through the reverse of palette, so palette
+// should not have 2 colors for the same value. The image covers offsets from
+// (0,0) to the largest offset holding data. Empty tiles get the color mapped
+// to nil in palette, or are transparent if there is none.
+//
+// It is an error if any tile holds a value that is not in the palette, or if
+// any tile is at a negative offset.
+func WriteImage(grid Grid, palette Palette) (*image.RGBA, error) {
+	_, toOffset, err := offsetLayout(grid)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := reversePalette(palette)
+
+	w, h := 0, 0
+	var unknown, negative []Loc
+	for k, v := range grid.Map() {
+		col, row := toOffset(k)
+		if col < 0 || row < 0 {
+			negative = append(negative, k)
+		}
+		if _, ok := colors.color(v); !ok {
+			unknown = append(unknown, k)
+		}
+		if col >= w {
+			w = col + 1
+		}
+		if row >= h {
+			h = row + 1
+		}
+	}
+	if len(unknown) > 0 {
+		sortLocs(unknown)
+		return nil, fmt.Errorf("%d tiles have values not in the palette, at %v", len(unknown), listLocs(unknown))
+	}
+	if len(negative) > 0 {
+		sortLocs(negative)
+		return nil, fmt.Errorf("%d tiles are at negative offsets, at %v", len(negative), listLocs(negative))
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if empty, ok := colors.color(nil); ok {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetRGBA(x, y, empty)
+			}
+		}
+	}
+	for k, v := range grid.Map() {
+		col, row := toOffset(k)
+		p := offsetToPoint(col, row, img.Bounds())
+		c, _ := colors.color(v)
+		img.SetRGBA(p.X, p.Y, c)
+	}
+	return img, nil
+}
+
+// paletteColors is the reverse of a Palette, mapping values to colors.
+type paletteColors struct {
+	comparable map[interface{}]color.RGBA
+	// the values which cannot be map keys, searched with reflect.DeepEqual
+	others      []interface{}
+	otherColors []color.RGBA
+}
+
+func reversePalette(palette Palette) *paletteColors {
+	pc := &paletteColors{comparable: make(map[interface{}]color.RGBA, len(palette))}
+	for c, v := range palette {
+		if isComparable(v) {
+			pc.comparable[v] = c
+		} else {
+			pc.others = append(pc.others, v)
+			pc.otherColors = append(pc.otherColors, c)
+		}
+	}
+	return pc
+}
+
+// color gets the color of v, and false if v is not in the palette.
+func (pc *paletteColors) color(v interface{}) (color.RGBA, bool) {
+	if isComparable(v) {
+		c, ok := pc.comparable[v]
+		return c, ok
+	}
+	for i, o := range pc.others {
+		if reflect.DeepEqual(o, v) {
+			return pc.otherColors[i], true
+		}
+	}
+	return color.RGBA{}, false
+}
+
+// isComparable tests if v can be used as a map key. This checks the values
+// in v's interface fields too, as a struct holding a slice in one can't be.
+func isComparable(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).Comparable()
+}
+
+// DecodeImage reads a PNG (or any other registered image format) from r
+// into grid. See ReadImage.
+func DecodeImage(r io.Reader, grid Grid, palette Palette) error {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+	return ReadImage(grid, img, palette)
+}
+
+// EncodePNG writes the data of grid to w as a PNG. See WriteImage.
+func EncodePNG(w io.Writer, grid Grid, palette Palette) error {
+	img, err := WriteImage(grid, palette)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// listLocs formats a few of the locs for an error message.
+func listLocs(locs []Loc) string {
+	if len(locs) > maxListedPixels {
+		return fmt.Sprintf("%v and %d more", locs[:maxListedPixels], len(locs)-maxListedPixels)
+	}
+	return fmt.Sprint(locs)
+}
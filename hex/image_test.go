@@ -0,0 +1,160 @@
+package hex
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	black = color.RGBA{0, 0, 0, 255}
+	white = color.RGBA{255, 255, 255, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	red   = color.RGBA{255, 0, 0, 255}
+)
+
+func TestReadWriteImage(t *testing.T) {
+	palette := Palette{black: "wall", blue: "water", white: nil}
+	tests := []struct {
+		name string
+		grid func() Grid
+	}{
+		{"square", func() Grid { return NewSquareGrid(1, 0) }},
+		{"hex pointy", func() Grid { return NewHexGrid(1, PointyTop) }},
+		{"hex flat", func() Grid { return NewHexGrid(1, FlatTop) }},
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			src.SetRGBA(x, y, []color.RGBA{black, blue, white}[(x+2*y)%3])
+		}
+	}
+	src.SetRGBA(4, 0, black) // so the image isn't cropped on export
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := tt.grid()
+			if err := ReadImage(grid, src, palette); err != nil {
+				t.Fatal(err)
+			}
+			if n := len(grid.Map()); n != 14 {
+				t.Errorf("ReadImage() set %d tiles, want 14", n)
+			}
+
+			buf := &bytes.Buffer{}
+			if err := EncodePNG(buf, grid, palette); err != nil {
+				t.Fatal(err)
+			}
+			got := tt.grid()
+			if err := DecodeImage(buf, got, palette); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Map(), grid.Map()) {
+				t.Errorf("grid changed after writing and reading an image")
+			}
+		})
+	}
+}
+
+func TestReadImage_UnknownColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			img.SetRGBA(x, y, black)
+		}
+	}
+	img.SetRGBA(2, 0, red)
+	img.SetRGBA(1, 1, red)
+
+	grid := NewSquareGrid(1, 0)
+	err := ReadImage(grid, img, Palette{black: 1})
+	var unknown *UnknownColorError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("ReadImage() error = %v, want *UnknownColorError", err)
+	}
+	if want := []image.Point{{2, 0}, {1, 1}}; !reflect.DeepEqual(unknown.Pixels, want) {
+		t.Errorf("UnknownColorError.Pixels = %v, want %v", unknown.Pixels, want)
+	}
+	if len(grid.Map()) != 0 {
+		t.Errorf("ReadImage() changed the grid despite an error")
+	}
+}
+
+func TestWriteImage_Errors(t *testing.T) {
+	grid := NewHexGrid(1, PointyTop)
+	grid.Set(0, 0, "wall")
+	grid.Set(1, 1, "lava")
+	if _, err := WriteImage(grid, Palette{black: "wall"}); err == nil {
+		t.Error("WriteImage() with a value not in the palette did not fail")
+	}
+
+	grid.Set(1, 1, nil)
+	grid.Set(0, -1, "wall")
+	if _, err := WriteImage(grid, Palette{black: "wall"}); err == nil {
+		t.Error("WriteImage() with a negative offset did not fail")
+	}
+
+	if _, err := WriteImage(NewSyncGrid(grid), Palette{black: "wall"}); err == nil {
+		t.Error("WriteImage() with an unsupported grid did not fail")
+	}
+}
+
+func TestWriteImage_Uncomparable(t *testing.T) {
+	grid := NewSquareGrid(1, 0)
+	grid.Set(0, 0, []int{1})
+	grid.Set(1, 0, "wall")
+	if _, err := WriteImage(grid, Palette{black: "wall"}); err == nil || !strings.Contains(err.Error(), "[[0 0]]") {
+		t.Errorf("WriteImage() error = %v, want one naming [0 0]", err)
+	}
+
+	img, err := WriteImage(grid, Palette{black: "wall", red: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(0, 0); got != red {
+		t.Errorf("pixel (0,0) = %v, want %v", got, red)
+	}
+	if got := img.RGBAAt(1, 0); got != black {
+		t.Errorf("pixel (1,0) = %v, want %v", got, black)
+	}
+
+	// a struct type is comparable, but not with a slice in an interface field
+	type tile struct{ Items interface{} }
+	grid.Set(0, 0, tile{[]int{1}})
+	if _, err := WriteImage(grid, Palette{black: "wall"}); err == nil || !strings.Contains(err.Error(), "[[0 0]]") {
+		t.Errorf("WriteImage() error = %v, want one naming [0 0]", err)
+	}
+	img, err = WriteImage(grid, Palette{black: "wall", red: tile{[]int{1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(0, 0); got != red {
+		t.Errorf("pixel (0,0) = %v, want %v", got, red)
+	}
+}
+
+func TestHexGrid_OffsetToAxial(t *testing.T) {
+	for _, o := range []HexagonOrientation{FlatTop, PointyTop} {
+		grid := NewHexGrid(1, o)
+		for row := -4; row <= 4; row++ {
+			for col := -4; col <= 4; col++ {
+				if c, r := grid.AxialToOffset(grid.OffsetToAxial(col, row)); c != col || r != row {
+					t.Fatalf("AxialToOffset(OffsetToAxial(%d, %d)) = %d, %d", col, row, c, r)
+				}
+			}
+		}
+	}
+
+	// in a pointy topped grid, offset columns zigzag but stay in place
+	grid := NewHexGrid(1, PointyTop)
+	x0, _ := grid.Center(grid.OffsetToAxial(3, 0).CR())
+	x2, _ := grid.Center(grid.OffsetToAxial(3, 2).CR())
+	if x0 != x2 {
+		t.Errorf("offset column 3 moves from x=%v to x=%v", x0, x2)
+	}
+}
@@ -0,0 +1,286 @@
+package hex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Layer is a named set of per-tile data stored on a LayeredGrid. The typed
+// storage is reached through a *TypedLayer, obtained with AddLayer or LayerOf.
+type Layer interface {
+	Name() string
+	Has(c, r int) bool
+	Delete(c, r int)
+	Locs() []Loc
+	Len() int
+	Encode(w io.Writer) error
+	Decode(r io.Reader) error
+}
+
+// TypedLayer is a Layer whose values are all of type T.
+type TypedLayer[T any] struct {
+	name string
+	Data map[Loc]T
+}
+
+// NewTypedLayer creates an empty layer holding values of type T.
+func NewTypedLayer[T any](name string) *TypedLayer[T] {
+	return &TypedLayer[T]{
+		name: name,
+		Data: make(map[Loc]T),
+	}
+}
+
+// Name gets the name of the layer.
+func (l *TypedLayer[T]) Name() string { return l.name }
+
+// Get returns the data at (c,r) and a boolean indicating whether or not data
+// existed at that location.
+func (l *TypedLayer[T]) Get(c, r int) (data T, ok bool) {
+	data, ok = l.Data[Loc{c, r}]
+	return
+}
+
+// Set sets the data at (c,r).
+func (l *TypedLayer[T]) Set(c, r int, data T) {
+	l.Data[Loc{c, r}] = data
+}
+
+// Has says if the layer has data at (c,r).
+func (l *TypedLayer[T]) Has(c, r int) bool {
+	_, ok := l.Data[Loc{c, r}]
+	return ok
+}
+
+// Delete removes the data at (c,r).
+func (l *TypedLayer[T]) Delete(c, r int) {
+	delete(l.Data, Loc{c, r})
+}
+
+// Map gets access to the layer's data, for use in "range", etc.
+func (l *TypedLayer[T]) Map() map[Loc]T {
+	return l.Data
+}
+
+// Locs gets the locations holding data in this layer, in no particular order.
+func (l *TypedLayer[T]) Locs() []Loc {
+	locs := make([]Loc, 0, len(l.Data))
+	for k := range l.Data {
+		locs = append(locs, k)
+	}
+	return locs
+}
+
+// Len is the number of tiles holding data in this layer.
+func (l *TypedLayer[T]) Len() int { return len(l.Data) }
+
+// layerTile is the serialized form of a single tile's data.
+type layerTile[T any] struct {
+	C int `json:"c"`
+	R int `json:"r"`
+	V T   `json:"v"`
+}
+
+// layerFile is the serialized form of a layer.
+type layerFile[T any] struct {
+	Name  string         `json:"name"`
+	Tiles []layerTile[T] `json:"tiles"`
+}
+
+// Encode writes the layer to w as JSON. Tiles are written in (c,r) order so
+// the output is stable.
+func (l *TypedLayer[T]) Encode(w io.Writer) error {
+	locs := l.Locs()
+	sortLocs(locs)
+
+	file := layerFile[T]{Name: l.name, Tiles: make([]layerTile[T], len(locs))}
+	for i, k := range locs {
+		file.Tiles[i] = layerTile[T]{C: k[0], R: k[1], V: l.Data[k]}
+	}
+
+	return json.NewEncoder(w).Encode(file)
+}
+
+// Decode reads a layer written by Encode from r, replacing the current
+// contents of the layer. The name stored in the data must match the layer's.
+func (l *TypedLayer[T]) Decode(r io.Reader) error {
+	var file layerFile[T]
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return fmt.Errorf("decoding layer %q: %w", l.name, err)
+	}
+	if file.Name != l.name {
+		return fmt.Errorf("decoding layer %q: data is for layer %q", l.name, file.Name)
+	}
+
+	l.Data = make(map[Loc]T, len(file.Tiles))
+	for _, t := range file.Tiles {
+		l.Data[Loc{t.C, t.R}] = t.V
+	}
+	return nil
+}
+
+// LayeredGrid is a single grid geometry (such as a HexGrid or SquareGrid)
+// shared by any number of named layers, each with its own storage. The data
+// stored in the geometry itself is not used.
+type LayeredGrid struct {
+	Geometry Grid
+	layers   map[string]Layer
+	order    []string
+}
+
+// NewLayeredGrid creates a layered grid with no layers using the given geometry.
+func NewLayeredGrid(geometry Grid) *LayeredGrid {
+	return &LayeredGrid{
+		Geometry: geometry,
+		layers:   make(map[string]Layer),
+	}
+}
+
+// AddLayer creates a new empty layer holding values of type T. It is an
+// error if a layer of that name already exists.
+func AddLayer[T any](lg *LayeredGrid, name string) (*TypedLayer[T], error) {
+	if _, exists := lg.layers[name]; exists {
+		return nil, fmt.Errorf("layer %q already exists", name)
+	}
+	l := NewTypedLayer[T](name)
+	lg.layers[name] = l
+	lg.order = append(lg.order, name)
+	return l, nil
+}
+
+// LayerOf gets the layer with the given name as a *TypedLayer[T]. It is an
+// error if the layer does not exist or does not hold values of type T.
+func LayerOf[T any](lg *LayeredGrid, name string) (*TypedLayer[T], error) {
+	l, ok := lg.layers[name]
+	if !ok {
+		return nil, fmt.Errorf("layer %q does not exist", name)
+	}
+	tl, ok := l.(*TypedLayer[T])
+	if !ok {
+		var zero T
+		return nil, fmt.Errorf("layer %q does not hold values of type %T", name, zero)
+	}
+	return tl, nil
+}
+
+// Layer gets the layer with the given name, and a boolean indicating whether
+// or not it exists.
+func (lg *LayeredGrid) Layer(name string) (l Layer, ok bool) {
+	l, ok = lg.layers[name]
+	return
+}
+
+// Layers gets all layers in the order they were added.
+func (lg *LayeredGrid) Layers() []Layer {
+	layers := make([]Layer, len(lg.order))
+	for i, name := range lg.order {
+		layers[i] = lg.layers[name]
+	}
+	return layers
+}
+
+// RemoveLayer deletes the layer with the given name, if it exists.
+func (lg *LayeredGrid) RemoveLayer(name string) {
+	if _, ok := lg.layers[name]; !ok {
+		return
+	}
+	delete(lg.layers, name)
+	for i, n := range lg.order {
+		if n == name {
+			lg.order = append(lg.order[:i], lg.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Locs gets every location holding data in at least one layer, sorted by
+// (c,r).
+func (lg *LayeredGrid) Locs() []Loc {
+	seen := make(map[Loc]bool)
+	for _, l := range lg.layers {
+		for _, k := range l.Locs() {
+			seen[k] = true
+		}
+	}
+
+	locs := make([]Loc, 0, len(seen))
+	for k := range seen {
+		locs = append(locs, k)
+	}
+	sortLocs(locs)
+	return locs
+}
+
+// Predicate tests a single tile of a LayeredGrid.
+type Predicate func(c, r int) bool
+
+// Where gets the locations, sorted by (c,r), that hold data in at least one
+// layer and satisfy all the predicates. For example, water tiles with no unit:
+//
+//	lg.Where(Is(terrain, Water), Not(Has(units)))
+func (lg *LayeredGrid) Where(preds ...Predicate) []Loc {
+	locs := lg.Locs()
+	matches := locs[:0]
+	for _, k := range locs {
+		ok := true
+		for _, p := range preds {
+			if !p(k.CR()) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matches = append(matches, k)
+		}
+	}
+	return matches
+}
+
+// WriteLayer encodes the named layer to w.
+func (lg *LayeredGrid) WriteLayer(w io.Writer, name string) error {
+	l, ok := lg.layers[name]
+	if !ok {
+		return fmt.Errorf("layer %q does not exist", name)
+	}
+	return l.Encode(w)
+}
+
+// ReadLayer decodes data written by WriteLayer into the named layer, which
+// must already exist with the correct type.
+func (lg *LayeredGrid) ReadLayer(r io.Reader, name string) error {
+	l, ok := lg.layers[name]
+	if !ok {
+		return fmt.Errorf("layer %q does not exist", name)
+	}
+	return l.Decode(r)
+}
+
+// Is makes a Predicate that is true where the layer holds v.
+func Is[T comparable](l *TypedLayer[T], v T) Predicate {
+	return func(c, r int) bool {
+		data, ok := l.Get(c, r)
+		return ok && data == v
+	}
+}
+
+// Has makes a Predicate that is true where the layer holds any data.
+func Has(l Layer) Predicate {
+	return l.Has
+}
+
+// Not makes a Predicate that negates p.
+func Not(p Predicate) Predicate {
+	return func(c, r int) bool { return !p(c, r) }
+}
+
+// sortLocs sorts locations by column, then row.
+func sortLocs(locs []Loc) {
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i][0] != locs[j][0] {
+			return locs[i][0] < locs[j][0]
+		}
+		return locs[i][1] < locs[j][1]
+	})
+}
@@ -0,0 +1,100 @@
+package hex
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type terrain int
+
+const (
+	land terrain = iota
+	water
+)
+
+func TestLayeredGrid_Where(t *testing.T) {
+	lg := NewLayeredGrid(NewHexGrid(1, PointyTop))
+	ground, err := AddLayer[terrain](lg, "terrain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	units, err := AddLayer[string](lg, "units")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ground.Set(0, 0, water)
+	ground.Set(1, 0, water)
+	ground.Set(2, 0, land)
+	units.Set(1, 0, "boat")
+	units.Set(5, 5, "bird")
+
+	tests := []struct {
+		name  string
+		preds []Predicate
+		want  []Loc
+	}{
+		{"all", nil, []Loc{{0, 0}, {1, 0}, {2, 0}, {5, 5}}},
+		{"water", []Predicate{Is(ground, water)}, []Loc{{0, 0}, {1, 0}}},
+		{"water no unit", []Predicate{Is(ground, water), Not(Has(units))}, []Loc{{0, 0}}},
+		{"unit no terrain", []Predicate{Has(units), Not(Has(ground))}, []Loc{{5, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lg.Where(tt.preds...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LayeredGrid.Where() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayeredGrid_Layers(t *testing.T) {
+	lg := NewLayeredGrid(NewSquareGrid(1, 0))
+	if _, err := AddLayer[int](lg, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddLayer[int](lg, "a"); err == nil {
+		t.Error("AddLayer() with duplicate name did not fail")
+	}
+	if _, err := LayerOf[string](lg, "a"); err == nil {
+		t.Error("LayerOf() with wrong type did not fail")
+	}
+	if _, err := LayerOf[int](lg, "b"); err == nil {
+		t.Error("LayerOf() with missing layer did not fail")
+	}
+	if _, err := LayerOf[int](lg, "a"); err != nil {
+		t.Errorf("LayerOf() error = %v", err)
+	}
+
+	lg.RemoveLayer("a")
+	if len(lg.Layers()) != 0 {
+		t.Errorf("RemoveLayer() left %d layers", len(lg.Layers()))
+	}
+}
+
+func TestLayeredGrid_ReadWriteLayer(t *testing.T) {
+	src := NewLayeredGrid(NewHexGrid(1, FlatTop))
+	fog, _ := AddLayer[float64](src, "fog")
+	fog.Set(0, 0, 0.5)
+	fog.Set(-3, 2, 1)
+
+	buf := &bytes.Buffer{}
+	if err := src.WriteLayer(buf, "fog"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewLayeredGrid(NewHexGrid(1, FlatTop))
+	got, _ := AddLayer[float64](dst, "fog")
+	if err := dst.ReadLayer(bytes.NewReader(buf.Bytes()), "fog"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Map(), fog.Map()) {
+		t.Errorf("ReadLayer() = %v, want %v", got.Map(), fog.Map())
+	}
+
+	other, _ := AddLayer[float64](dst, "other")
+	if err := other.Decode(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("Decode() into layer with different name did not fail")
+	}
+}
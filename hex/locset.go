@@ -0,0 +1,166 @@
+package hex
+
+// LocSet is a set of grid locations, such as a selection, a movement range or
+// an area of visibility. The shape operations take the Topology whose
+// connectivity (see Topology.Neighbors) should be used.
+type LocSet map[Loc]struct{}
+
+// NewLocSet creates a set holding the given locations.
+func NewLocSet(locs ...Loc) LocSet {
+	s := make(LocSet, len(locs))
+	s.Add(locs...)
+	return s
+}
+
+// Add puts the locations into the set.
+func (s LocSet) Add(locs ...Loc) {
+	for _, l := range locs {
+		s[l] = struct{}{}
+	}
+}
+
+// Remove takes the locations out of the set.
+func (s LocSet) Remove(locs ...Loc) {
+	for _, l := range locs {
+		delete(s, l)
+	}
+}
+
+// Has says if l is in the set.
+func (s LocSet) Has(l Loc) bool {
+	_, ok := s[l]
+	return ok
+}
+
+// Len is the number of locations in the set.
+func (s LocSet) Len() int { return len(s) }
+
+// Slice gets the locations in the set, sorted by (c,r).
+func (s LocSet) Slice() []Loc {
+	locs := make([]Loc, 0, len(s))
+	for l := range s {
+		locs = append(locs, l)
+	}
+	sortLocs(locs)
+	return locs
+}
+
+// Union makes a new set of the locations in either s or o.
+func (s LocSet) Union(o LocSet) LocSet {
+	u := make(LocSet, len(s)+len(o))
+	for l := range s {
+		u[l] = struct{}{}
+	}
+	for l := range o {
+		u[l] = struct{}{}
+	}
+	return u
+}
+
+// Intersect makes a new set of the locations in both s and o.
+func (s LocSet) Intersect(o LocSet) LocSet {
+	if len(o) < len(s) {
+		s, o = o, s
+	}
+	i := make(LocSet)
+	for l := range s {
+		if o.Has(l) {
+			i[l] = struct{}{}
+		}
+	}
+	return i
+}
+
+// Difference makes a new set of the locations in s but not in o.
+func (s LocSet) Difference(o LocSet) LocSet {
+	d := make(LocSet)
+	for l := range s {
+		if !o.Has(l) {
+			d[l] = struct{}{}
+		}
+	}
+	return d
+}
+
+// Dilate makes a new set of the locations within n steps of any location in
+// s, "growing" the set by n tiles in every direction.
+func (s LocSet) Dilate(g Topology, n int) LocSet {
+	d := s.Union(nil)
+	frontier := s.Slice()
+	for step := 0; step < n; step++ {
+		next := []Loc{}
+		for _, l := range frontier {
+			for _, nb := range g.Neighbors(l.CR()) {
+				if !d.Has(nb) {
+					d[nb] = struct{}{}
+					next = append(next, nb)
+				}
+			}
+		}
+		frontier = next
+	}
+	return d
+}
+
+// Erode makes a new set of the locations in s whose tiles within n steps are
+// all in s, "shrinking" the set by n tiles from every edge.
+func (s LocSet) Erode(g Topology, n int) LocSet {
+	e := s.Union(nil)
+	for step := 0; step < n; step++ {
+		e = e.Difference(e.Boundary(g))
+	}
+	return e
+}
+
+// Boundary makes a new set of the locations in s that have at least one
+// neighbor not in s.
+func (s LocSet) Boundary(g Topology) LocSet {
+	b := make(LocSet)
+	for l := range s {
+		for _, nb := range g.Neighbors(l.CR()) {
+			if !s.Has(nb) {
+				b[l] = struct{}{}
+				break
+			}
+		}
+	}
+	return b
+}
+
+// Centroid gets the average of the world coordinates of the centers of the
+// tiles in s. It is (0,0) for an empty set.
+func (s LocSet) Centroid(g Topology) (x, y float64) {
+	if len(s) == 0 {
+		return 0, 0
+	}
+	for l := range s {
+		cx, cy := g.Center(l.CR())
+		x += cx
+		y += cy
+	}
+	n := float64(len(s))
+	return x / n, y / n
+}
+
+// Bounds gets the smallest and largest column and row of the locations in
+// s, as the Locs (minC, minR) and (maxC, maxR). Both are (0,0) for an empty
+// set.
+func (s LocSet) Bounds() (min, max Loc) {
+	first := true
+	for l := range s {
+		if first {
+			min, max = l, l
+			first = false
+			continue
+		}
+		for i := range l {
+			if l[i] < min[i] {
+				min[i] = l[i]
+			}
+			if l[i] > max[i] {
+				max[i] = l[i]
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,78 @@
+package hex
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLocSet_Algebra(t *testing.T) {
+	a := NewLocSet(Loc{0, 0}, Loc{1, 0}, Loc{2, 0})
+	b := NewLocSet(Loc{2, 0}, Loc{3, 0})
+
+	tests := []struct {
+		name string
+		got  LocSet
+		want []Loc
+	}{
+		{"union", a.Union(b), []Loc{{0, 0}, {1, 0}, {2, 0}, {3, 0}}},
+		{"intersect", a.Intersect(b), []Loc{{2, 0}}},
+		{"difference", a.Difference(b), []Loc{{0, 0}, {1, 0}}},
+		{"difference reversed", b.Difference(a), []Loc{{3, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.Slice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if a.Len() != 3 || b.Len() != 2 {
+		t.Error("set operations changed their operands")
+	}
+}
+
+func TestLocSet_Shape(t *testing.T) {
+	tests := []struct {
+		name     string
+		grid     Topology
+		n        int
+		dilated  int // size of a single tile dilated by n
+		boundary int // size of the boundary of that
+	}{
+		{"hex", NewHexGrid(1, PointyTop), 2, 19, 12},
+		{"square", NewSquareGrid(1, 0), 2, 13, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			center := NewLocSet(Loc{4, -1})
+			d := center.Dilate(tt.grid, tt.n)
+			if d.Len() != tt.dilated {
+				t.Errorf("Dilate() has %d tiles, want %d", d.Len(), tt.dilated)
+			}
+			if b := d.Boundary(tt.grid); b.Len() != tt.boundary {
+				t.Errorf("Boundary() has %d tiles, want %d", b.Len(), tt.boundary)
+			}
+			if e := d.Erode(tt.grid, tt.n); !reflect.DeepEqual(e, center) {
+				t.Errorf("Erode(Dilate()) = %v, want %v", e.Slice(), center.Slice())
+			}
+			if e := d.Erode(tt.grid, tt.n+1); e.Len() != 0 {
+				t.Errorf("Erode() too far = %v, want empty", e.Slice())
+			}
+
+			// dilated shapes are symmetric about their center
+			x, y := d.Centroid(tt.grid)
+			cx, cy := tt.grid.ToWorld(4, -1)
+			if math.Abs(x-cx) > epsilon || math.Abs(y-cy) > epsilon {
+				t.Errorf("Centroid() = %v, %v, want %v, %v", x, y, cx, cy)
+			}
+		})
+	}
+}
+
+func TestLocSet_Bounds(t *testing.T) {
+	min, max := NewLocSet(Loc{3, -2}, Loc{-1, 5}, Loc{0, 0}).Bounds()
+	if min != (Loc{-1, -2}) || max != (Loc{3, 5}) {
+		t.Errorf("Bounds() = %v, %v, want [-1 -2], [3 5]", min, max)
+	}
+}
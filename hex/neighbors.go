@@ -0,0 +1,112 @@
+package hex
+
+// Topology is a Grid which knows where the centers of its grid units are and
+// which of them are next to each other, as needed to walk over the grid.
+// HexGrid, SquareGrid and SyncGrid are Topologies.
+type Topology interface {
+	Grid
+	Center(c, r int) (float64, float64) // world coords of the center of the grid unit at (c,r)
+	Neighbors(c, r int) []Loc           // locations of the grid units sharing an edge with (c,r)
+	Distance(a, b Loc) int              // number of steps between grid units a and b
+}
+
+// axial offsets to the 6 neighbors of a hexagon, starting on the right and
+// going counter-clockwise (for pointy topped hexagons).
+var hexDirections = [6]Loc{{1, 0}, {0, 1}, {-1, 1}, {-1, 0}, {0, -1}, {1, -1}}
+
+// offsets to the 4 neighbors of a square, starting on the right and going
+// counter-clockwise.
+var squareDirections = [4]Loc{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// Neighbors gets the locations of the 6 hexagons sharing an edge with the
+// hexagon at (c,r).
+func (grid *HexGrid) Neighbors(c, r int) []Loc {
+	return neighbors(c, r, hexDirections[:])
+}
+
+// Distance gets the number of steps between hexagons a and b.
+func (grid *HexGrid) Distance(a, b Loc) int {
+	dc, dr := a[0]-b[0], a[1]-b[1]
+	return (abs(dc) + abs(dr) + abs(dc+dr)) / 2
+}
+
+// Neighbors gets the locations of the 4 squares sharing an edge with the
+// square at (c,r).
+func (grid *SquareGrid) Neighbors(c, r int) []Loc {
+	return neighbors(c, r, squareDirections[:])
+}
+
+// Distance gets the number of steps between squares a and b, moving only
+// between squares that share an edge (ie the manhattan distance).
+func (grid *SquareGrid) Distance(a, b Loc) int {
+	return abs(a[0]-b[0]) + abs(a[1]-b[1])
+}
+
+// Within gets the locations of all tiles no more than n steps from center,
+// including center itself. For a HexGrid this is a hexagon of "radius" n.
+func Within(g Topology, center Loc, n int) []Loc {
+	seen := map[Loc]bool{center: true}
+	locs := []Loc{center}
+	frontier := []Loc{center}
+	for step := 0; step < n; step++ {
+		next := []Loc{}
+		for _, l := range frontier {
+			for _, nb := range g.Neighbors(l.CR()) {
+				if !seen[nb] {
+					seen[nb] = true
+					locs = append(locs, nb)
+					next = append(next, nb)
+				}
+			}
+		}
+		frontier = next
+	}
+	return locs
+}
+
+// Line gets the locations of the tiles on a line drawn from the center of
+// tile a to the center of tile b, inclusive.
+func Line(g Topology, a, b Loc) []Loc {
+	n := g.Distance(a, b)
+	if n == 0 {
+		return []Loc{a}
+	}
+
+	// work between tile centers in fractional grid coords. nudge the line
+	// slightly so that it doesn't run exactly along tile edges, where rounding
+	// would be ambiguous.
+	const nudge = 1e-6
+	ac, ar := g.ToGrid(g.Center(a.CR()))
+	bc, br := g.ToGrid(g.Center(b.CR()))
+	ac, ar, bc, br = ac+nudge, ar+nudge, bc+nudge, br+nudge
+
+	locs := []Loc{a}
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		c, r := g.Tile(lerp(ac, bc, t), lerp(ar, br, t))
+		if l := (Loc{c, r}); l != locs[len(locs)-1] {
+			locs = append(locs, l)
+		}
+	}
+	return locs
+}
+
+// neighbors adds each of the offsets in dirs to (c,r).
+func neighbors(c, r int, dirs []Loc) []Loc {
+	locs := make([]Loc, len(dirs))
+	for i, d := range dirs {
+		locs[i] = Loc{c + d[0], r + d[1]}
+	}
+	return locs
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
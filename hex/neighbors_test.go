@@ -0,0 +1,65 @@
+package hex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		name string
+		grid Topology
+		n    int
+		want int
+	}{
+		{"hex 0", NewHexGrid(1, PointyTop), 0, 1},
+		{"hex 1", NewHexGrid(1, PointyTop), 1, 7},
+		{"hex 3", NewHexGrid(1, FlatTop), 3, 37},
+		{"square 1", NewSquareGrid(1, 0), 1, 5},
+		{"square 2", NewSquareGrid(1, 0), 2, 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			center := Loc{2, -1}
+			got := Within(tt.grid, center, tt.n)
+			if len(got) != tt.want {
+				t.Errorf("Within() found %d tiles, want %d", len(got), tt.want)
+			}
+			for _, l := range got {
+				if d := tt.grid.Distance(center, l); d > tt.n {
+					t.Errorf("Within() included %v at distance %d", l, d)
+				}
+			}
+		})
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name string
+		grid Topology
+		a, b Loc
+		want []Loc
+	}{
+		{"hex point", NewHexGrid(1, PointyTop), Loc{1, 1}, Loc{1, 1}, []Loc{{1, 1}}},
+		{"hex row", NewHexGrid(1, PointyTop), Loc{0, 0}, Loc{3, 0}, []Loc{{0, 0}, {1, 0}, {2, 0}, {3, 0}}},
+		{"hex diagonal", NewHexGrid(1, FlatTop), Loc{0, 0}, Loc{2, -2}, []Loc{{0, 0}, {1, -1}, {2, -2}}},
+		{"square row", NewSquareGrid(1, 0), Loc{0, 0}, Loc{0, 2}, []Loc{{0, 0}, {0, 1}, {0, 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Line(tt.grid, tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Line() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHexGrid_Neighbors(t *testing.T) {
+	grid := NewHexGrid(1, PointyTop)
+	for _, n := range grid.Neighbors(3, -2) {
+		if d := grid.Distance(Loc{3, -2}, n); d != 1 {
+			t.Errorf("neighbor %v is at distance %d", n, d)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package hex
+
+import "container/heap"
+
+// TilePredicate decides whether the tile at l, which holds data, should be
+// included in a region.
+type TilePredicate func(l Loc, data interface{}) bool
+
+// MoveCost gives the cost of moving into the tile at l, which holds data, and
+// whether the tile can be entered at all. Costs must not be negative.
+type MoveCost func(l Loc, data interface{}) (cost int, ok bool)
+
+// FloodFill gets the locations of the tiles connected to start, through
+// neighboring tiles, for which include returns true. Only tiles holding data
+// are considered. If start itself is not included, nil is returned.
+//
+// For example, all tiles connected to (c,r) with the same value:
+//
+//	v, _ := grid.Get(c, r)
+//	FloodFill(grid, Loc{c, r}, func(l Loc, data interface{}) bool { return data == v })
+func FloodFill(g Topology, start Loc, include TilePredicate) []Loc {
+	data, ok := g.Get(start.CR())
+	if !ok || !include(start, data) {
+		return nil
+	}
+	return fill(g, start, include, map[Loc]bool{start: true})
+}
+
+// Components labels the connected regions ("islands") of tiles for which
+// include returns true. Only tiles holding data are considered. The returned
+// slice holds the locations of each region; the index of a region is its
+// label. Regions are ordered by their lowest (c,r) location.
+func Components(g Topology, include TilePredicate) [][]Loc {
+	locs := make([]Loc, 0, len(g.Map()))
+	for k, v := range g.Map() {
+		if include(k, v) {
+			locs = append(locs, k)
+		}
+	}
+	sortLocs(locs)
+
+	var regions [][]Loc
+	seen := make(map[Loc]bool)
+	for _, k := range locs {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		regions = append(regions, fill(g, k, include, seen))
+	}
+	return regions
+}
+
+// fill does a breadth first search from start, which must already be marked
+// in seen.
+func fill(g Topology, start Loc, include TilePredicate, seen map[Loc]bool) []Loc {
+	region := []Loc{start}
+	for i := 0; i < len(region); i++ {
+		for _, nb := range g.Neighbors(region[i].CR()) {
+			if seen[nb] {
+				continue
+			}
+			if data, ok := g.Get(nb.CR()); ok && include(nb, data) {
+				seen[nb] = true
+				region = append(region, nb)
+			}
+		}
+	}
+	return region
+}
+
+// MovementRange finds the tiles reachable from start by spending no more than
+// budget movement points, where cost gives the price of entering each tile.
+// Only tiles holding data can be entered. The result maps each reachable tile
+// (including start) to the most budget that can be left upon reaching it.
+func MovementRange(g Topology, start Loc, budget int, cost MoveCost) map[Loc]int {
+	remaining := map[Loc]int{start: budget}
+	q := &moveQueue{{start, budget}}
+	for q.Len() > 0 {
+		cur := heap.Pop(q).(move)
+		if cur.left < remaining[cur.loc] {
+			continue // stale entry; a better way here was already found
+		}
+
+		for _, nb := range g.Neighbors(cur.loc.CR()) {
+			data, ok := g.Get(nb.CR())
+			if !ok {
+				continue
+			}
+			c, ok := cost(nb, data)
+			if !ok || c > cur.left {
+				continue
+			}
+			left := cur.left - c
+			if best, seen := remaining[nb]; seen && best >= left {
+				continue
+			}
+			remaining[nb] = left
+			heap.Push(q, move{nb, left})
+		}
+	}
+	return remaining
+}
+
+// move is a tile reached with some budget left over.
+type move struct {
+	loc  Loc
+	left int
+}
+
+// moveQueue is a max-heap of moves by budget left.
+type moveQueue []move
+
+func (q moveQueue) Len() int            { return len(q) }
+func (q moveQueue) Less(i, j int) bool  { return q[i].left > q[j].left }
+func (q moveQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *moveQueue) Push(x interface{}) { *q = append(*q, x.(move)) }
+func (q *moveQueue) Pop() interface{} {
+	old := *q
+	m := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return m
+}
@@ -0,0 +1,110 @@
+package hex
+
+import (
+	"reflect"
+	"testing"
+)
+
+// makeSquareMap makes a square grid from rows of text, where the first row is
+// r=0 and each character is the data for column c.
+func makeSquareMap(rows ...string) *SquareGrid {
+	grid := NewSquareGrid(1, 0)
+	for r, row := range rows {
+		for c, ch := range row {
+			if ch != ' ' {
+				grid.Set(c, r, ch)
+			}
+		}
+	}
+	return grid
+}
+
+func is(v rune) TilePredicate {
+	return func(l Loc, data interface{}) bool { return data == v }
+}
+
+func TestFloodFill(t *testing.T) {
+	grid := makeSquareMap(
+		"..#",
+		"#.#",
+		"#..",
+		"## ",
+	)
+	tests := []struct {
+		name  string
+		start Loc
+		pred  TilePredicate
+		want  int
+	}{
+		{"dots", Loc{0, 0}, is('.'), 5},
+		{"left wall", Loc{0, 1}, is('#'), 4},
+		{"right wall", Loc{2, 0}, is('#'), 2},
+		{"not included", Loc{0, 0}, is('#'), 0},
+		{"empty start", Loc{2, 3}, is('.'), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FloodFill(grid, tt.start, tt.pred); len(got) != tt.want {
+				t.Errorf("FloodFill() = %v, want %d tiles", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComponents(t *testing.T) {
+	grid := makeSquareMap(
+		"#.#",
+		"#.#",
+		"..#",
+		"#..",
+	)
+	got := Components(grid, is('#'))
+	want := [][]Loc{{{0, 0}, {0, 1}}, {{0, 3}}, {{2, 0}, {2, 1}, {2, 2}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Components() = %v, want %v", got, want)
+	}
+
+	// on a hex grid (0,1) and (1,0) are neighbors, joining the islands
+	hgrid := NewHexGrid(1, PointyTop)
+	hgrid.Set(0, 1, '#')
+	hgrid.Set(1, 0, '#')
+	hgrid.Set(3, 3, '#')
+	if got := Components(hgrid, is('#')); len(got) != 2 {
+		t.Errorf("Components() on hex grid = %v, want 2 regions", got)
+	}
+}
+
+func TestMovementRange(t *testing.T) {
+	// '.' costs 1, '~' costs 3, '#' can't be entered
+	grid := makeSquareMap(
+		"...~.",
+		".#.~.",
+		"....#",
+	)
+	cost := func(l Loc, data interface{}) (int, bool) {
+		switch data {
+		case '.':
+			return 1, true
+		case '~':
+			return 3, true
+		}
+		return 0, false
+	}
+
+	got := MovementRange(grid, Loc{0, 0}, 3, cost)
+	want := map[Loc]int{
+		{0, 0}: 3, {1, 0}: 2, {2, 0}: 1, {0, 1}: 2,
+		{0, 2}: 1, {2, 1}: 0, {1, 2}: 0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MovementRange() = %v, want %v", got, want)
+	}
+
+	got = MovementRange(grid, Loc{2, 0}, 4, cost)
+	if left, ok := got[Loc{4, 0}]; !ok || left != 0 {
+		t.Errorf("MovementRange() across water left %d, %v, want 0, true", left, ok)
+	}
+	if _, ok := got[Loc{1, 1}]; ok {
+		t.Errorf("MovementRange() entered a wall")
+	}
+}
@@ -1,6 +1,7 @@
 package hex
 
 import (
+	"image"
 	"math"
 
 	"github.com/go-gl/mathgl/mgl64"
@@ -17,14 +18,33 @@ type Grid interface {
 	Tile(c, r float64) (int, int) // converts fractional grid coords to the integer location of the grid unit
 }
 
-// SquareGrid represents a grid of squares.
+// CellAnchor describes where within a square of a SquareGrid the square's
+// integer grid coordinates fall.
+type CellAnchor int
+
+// constants for the 2 types of cell anchors
+const (
+	// CenterAnchor puts integer coordinates at the centers of squares, so the
+	// square at (c,r) covers grid coordinates [c-0.5, c+0.5).
+	CenterAnchor CellAnchor = iota
+	// CornerAnchor puts integer coordinates at the "bottom left" corners of
+	// squares, so the square at (c,r) covers grid coordinates [c, c+1). This
+	// matches images, where pixel (x,y) covers [x, x+1).
+	CornerAnchor CellAnchor = iota
+)
+
+// SquareGrid represents a grid of squares. By default the grid's integer
+// coordinates are the centers of the squares; set Anchor to CornerAnchor to
+// put them at the corners instead.
 type SquareGrid struct {
 	SideLength   float64
 	Circumradius float64
 	Inradius     float64
 	Orientation  float64
+	Anchor       CellAnchor
 	Data         map[Loc]interface{}
 	toWorldMat   mgl64.Mat2
+	toGridMat    mgl64.Mat2 // inverse of toWorldMat
 }
 
 // NewSquareGrid creates the data structure to represent a square grid. The
@@ -39,6 +59,7 @@ func NewSquareGrid(sideLength, angleRadians float64) *SquareGrid {
 	}
 
 	grid.toWorldMat = mgl64.Rotate2D(angleRadians).Mul(sideLength)
+	grid.toGridMat = grid.toWorldMat.Inv()
 
 	return grid
 }
@@ -51,7 +72,7 @@ func (grid *SquareGrid) ToWorld(c, r float64) (float64, float64) {
 
 // ToGrid converts world (screen) coordinates to grid coordinates.
 func (grid *SquareGrid) ToGrid(x, y float64) (float64, float64) {
-	g := grid.toWorldMat.Inv().Mul2x1(mgl64.Vec2{x, y})
+	g := grid.toGridMat.Mul2x1(mgl64.Vec2{x, y})
 	return g.X(), g.Y()
 }
 
@@ -61,7 +82,7 @@ func (grid *SquareGrid) Vertices(c, r int) (verts []mgl64.Vec2) {
 	verts = make([]mgl64.Vec2, 4, 4)
 	offset := grid.Orientation + math.Pi/4 // rotation + 45 deg
 
-	x, y := grid.ToWorld(float64(c), float64(r))
+	x, y := grid.Center(c, r)
 	for i := 0.0; i < 4; i++ {
 		theta := i*math.Pi/2 + offset
 		sin, cos := math.Sincos(theta)
@@ -94,8 +115,44 @@ func (grid *SquareGrid) Map() map[Loc]interface{} {
 	return grid.Data
 }
 
+// Center gets the world coordinates of the center of the square at (c,r).
+func (grid *SquareGrid) Center(c, r int) (float64, float64) {
+	if grid.Anchor == CornerAnchor {
+		return grid.ToWorld(float64(c)+0.5, float64(r)+0.5)
+	}
+	return grid.ToWorld(float64(c), float64(r))
+}
+
 // Tile returns the grid coords (column and row) of the square containing
 // the given fractional grid coordinates.
 func (grid *SquareGrid) Tile(c, r float64) (int, int) {
+	if grid.Anchor == CornerAnchor {
+		return int(math.Floor(c)), int(math.Floor(r))
+	}
 	return int(math.Round(c)), int(math.Round(r))
 }
+
+// PointToLoc gets the location of the square for pixel p of an image with
+// the given bounds. The image's bottom left pixel is square (0,0), and the
+// top row of the image has the highest r, since +y is up in the grid.
+func (grid *SquareGrid) PointToLoc(p image.Point, bounds image.Rectangle) Loc {
+	return Loc(pointToOffset(p, bounds))
+}
+
+// LocToPoint gets the pixel of an image with the given bounds for the square
+// at l. It is the inverse of PointToLoc.
+func (grid *SquareGrid) LocToPoint(l Loc, bounds image.Rectangle) image.Point {
+	return offsetToPoint(l[0], l[1], bounds)
+}
+
+// pointToOffset gets the offset (col,row) of pixel p of an image with the
+// given bounds, counting from the bottom left pixel.
+func pointToOffset(p image.Point, bounds image.Rectangle) [2]int {
+	return [2]int{p.X - bounds.Min.X, bounds.Max.Y - 1 - p.Y}
+}
+
+// offsetToPoint gets the pixel of an image with the given bounds at offset
+// (col,row). It is the inverse of pointToOffset.
+func offsetToPoint(col, row int, bounds image.Rectangle) image.Point {
+	return image.Point{X: bounds.Min.X + col, Y: bounds.Max.Y - 1 - row}
+}
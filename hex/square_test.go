@@ -1,8 +1,11 @@
 package hex
 
 import (
+	"image"
 	"math"
 	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
 )
 
 func TestNewSquareGrid(t *testing.T) {
@@ -10,3 +13,58 @@ func TestNewSquareGrid(t *testing.T) {
 	grid := NewSquareGrid(2, math.Pi/2)
 	t.Log(grid.toWorldMat)
 }
+
+func TestSquareGrid_Anchor(t *testing.T) {
+	for _, anchor := range []CellAnchor{CenterAnchor, CornerAnchor} {
+		grid := NewSquareGrid(2, 0.4)
+		grid.Anchor = anchor
+
+		for _, p := range randomPoints(2000) {
+			c, r := grid.Tile(grid.ToGrid(p.Elem()))
+			if !insideConvex(p, grid.Vertices(c, r)) {
+				t.Fatalf("anchor %d: %v is not inside the vertices of tile %d,%d", anchor, p, c, r)
+			}
+			if gc, gr := grid.Tile(grid.ToGrid(grid.Center(c, r))); gc != c || gr != r {
+				t.Fatalf("anchor %d: center of tile %d,%d is in tile %d,%d", anchor, c, r, gc, gr)
+			}
+		}
+	}
+
+	// corner anchored squares start at the origin
+	grid := NewSquareGrid(1, 0)
+	grid.Anchor = CornerAnchor
+	if x, y := grid.Center(0, 0); math.Abs(x-0.5) > epsilon || math.Abs(y-0.5) > epsilon {
+		t.Errorf("Center(0, 0) = %v, %v, want 0.5, 0.5", x, y)
+	}
+	if c, r := grid.Tile(0.99, 0.01); c != 0 || r != 0 {
+		t.Errorf("Tile(0.99, 0.01) = %d, %d, want 0, 0", c, r)
+	}
+}
+
+func TestSquareGrid_PointToLoc(t *testing.T) {
+	grid := NewSquareGrid(1, 0)
+	bounds := image.Rect(-2, 3, 8, 10)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Point{x, y}
+			if got := grid.LocToPoint(grid.PointToLoc(p, bounds), bounds); got != p {
+				t.Fatalf("LocToPoint(PointToLoc(%v)) = %v", p, got)
+			}
+		}
+	}
+	if got := grid.PointToLoc(image.Point{-2, 9}, bounds); got != (Loc{0, 0}) {
+		t.Errorf("PointToLoc(bottom left) = %v, want [0 0]", got)
+	}
+}
+
+// insideConvex says if p is inside the counter-clockwise convex polygon.
+func insideConvex(p mgl64.Vec2, poly []mgl64.Vec2) bool {
+	for i := range poly {
+		a, b := poly[i], poly[(i+1)%len(poly)]
+		edge, toP := b.Sub(a), p.Sub(a)
+		if edge[0]*toP[1]-edge[1]*toP[0] < -epsilon {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+package hex
+
+import (
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// number of independently locked partitions of a SyncGrid's data.
+const syncGridShards = 32
+
+// SyncGrid is a Topology that is safe for concurrent use by multiple
+// goroutines. It uses the geometry of another Topology (such as a HexGrid or
+// SquareGrid), but keeps its own data spread over several separately locked
+// shards so that writers to different tiles rarely contend.
+//
+// Readers that need to iterate over the whole grid should use Snapshot(),
+// which is cheap and does not block writers.
+type SyncGrid struct {
+	geometry Topology
+	shards   [syncGridShards]syncShard
+}
+
+// syncShard is one locked partition of a SyncGrid's data.
+type syncShard struct {
+	mu   sync.RWMutex
+	data map[Loc]interface{}
+	// shared is true when data is referenced by a Snapshot, in which case it
+	// must be copied before being written to.
+	shared bool
+}
+
+// NewSyncGrid creates a concurrency-safe grid with the same geometry as
+// geometry. Any data already in geometry is copied into the new grid.
+func NewSyncGrid(geometry Topology) *SyncGrid {
+	grid := &SyncGrid{geometry: geometry}
+	for i := range grid.shards {
+		grid.shards[i].data = make(map[Loc]interface{})
+	}
+	for k, v := range geometry.Map() {
+		grid.shard(k).data[k] = v
+	}
+	return grid
+}
+
+// shard gets the shard responsible for the tile at k.
+func (grid *SyncGrid) shard(k Loc) *syncShard {
+	return &grid.shards[shardIndex(k)]
+}
+
+// ToWorld converts grid coordinates to world coordinates.
+func (grid *SyncGrid) ToWorld(c, r float64) (float64, float64) {
+	return grid.geometry.ToWorld(c, r)
+}
+
+// ToGrid converts world coordinates to grid coordinates.
+func (grid *SyncGrid) ToGrid(x, y float64) (float64, float64) {
+	return grid.geometry.ToGrid(x, y)
+}
+
+// Vertices gets the vertices of the tile at (c,r) in world coordinates.
+func (grid *SyncGrid) Vertices(c, r int) []mgl64.Vec2 {
+	return grid.geometry.Vertices(c, r)
+}
+
+// Tile returns the grid coords of the tile containing the given fractional
+// grid coordinates.
+func (grid *SyncGrid) Tile(c, r float64) (int, int) {
+	return grid.geometry.Tile(c, r)
+}
+
+// Center gets the world coordinates of the center of the tile at (c,r).
+func (grid *SyncGrid) Center(c, r int) (float64, float64) {
+	return grid.geometry.Center(c, r)
+}
+
+// Neighbors gets the locations of the tiles sharing an edge with (c,r).
+func (grid *SyncGrid) Neighbors(c, r int) []Loc {
+	return grid.geometry.Neighbors(c, r)
+}
+
+// Distance gets the number of steps between tiles a and b.
+func (grid *SyncGrid) Distance(a, b Loc) int {
+	return grid.geometry.Distance(a, b)
+}
+
+// Get returns the data at (c,r) and a boolean indicating whether or not data
+// existed at that location.
+func (grid *SyncGrid) Get(c, r int) (data interface{}, ok bool) {
+	k := Loc{c, r}
+	s := grid.shard(k)
+	s.mu.RLock()
+	data, ok = s.data[k]
+	s.mu.RUnlock()
+	return
+}
+
+// Set sets the data at (c,r). If data is nil, the value at (c,r) is deleted.
+func (grid *SyncGrid) Set(c, r int, data interface{}) {
+	k := Loc{c, r}
+	s := grid.shard(k)
+	s.mu.Lock()
+	if s.shared {
+		// copy-on-write: a snapshot still refers to the current map
+		s.data = copyData(s.data)
+		s.shared = false
+	}
+	if data == nil {
+		delete(s.data, k)
+	} else {
+		s.data[k] = data
+	}
+	s.mu.Unlock()
+}
+
+// Map gets a copy of the grid's data, for use in "range", etc. Unlike HexGrid
+// and SquareGrid, changes to the returned map do not change the grid.
+func (grid *SyncGrid) Map() map[Loc]interface{} {
+	data := make(map[Loc]interface{})
+	for i := range grid.shards {
+		s := &grid.shards[i]
+		s.mu.RLock()
+		for k, v := range s.data {
+			data[k] = v
+		}
+		s.mu.RUnlock()
+	}
+	return data
+}
+
+// Len is the number of tiles holding data.
+func (grid *SyncGrid) Len() (n int) {
+	for i := range grid.shards {
+		s := &grid.shards[i]
+		s.mu.RLock()
+		n += len(s.data)
+		s.mu.RUnlock()
+	}
+	return
+}
+
+// Snapshot gets a read-only view of the grid's data as it is now. Taking a
+// snapshot does not copy any data; instead the next write to each part of the
+// grid copies that part. Snapshots may be read from any number of goroutines
+// without locking and are unaffected by later changes to the grid.
+//
+// Shards are captured one after another, so a snapshot taken during
+// concurrent writes may include some of those writes and not others.
+func (grid *SyncGrid) Snapshot() *Snapshot {
+	snap := &Snapshot{}
+	for i := range grid.shards {
+		s := &grid.shards[i]
+		s.mu.Lock()
+		s.shared = true
+		snap.shards[i] = s.data
+		s.mu.Unlock()
+	}
+	return snap
+}
+
+// Snapshot is an immutable view of a SyncGrid's data at some moment.
+type Snapshot struct {
+	shards [syncGridShards]map[Loc]interface{}
+}
+
+// Get returns the data at (c,r) and a boolean indicating whether or not data
+// existed at that location.
+func (snap *Snapshot) Get(c, r int) (data interface{}, ok bool) {
+	k := Loc{c, r}
+	data, ok = snap.shards[shardIndex(k)][k]
+	return
+}
+
+// Len is the number of tiles holding data.
+func (snap *Snapshot) Len() (n int) {
+	for _, m := range snap.shards {
+		n += len(m)
+	}
+	return
+}
+
+// Range calls f for each tile holding data, in no particular order. If f
+// returns false, iteration stops.
+func (snap *Snapshot) Range(f func(k Loc, data interface{}) bool) {
+	for _, m := range snap.shards {
+		for k, v := range m {
+			if !f(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Map gets a copy of the snapshot's data as a single map.
+func (snap *Snapshot) Map() map[Loc]interface{} {
+	data := make(map[Loc]interface{}, snap.Len())
+	for _, m := range snap.shards {
+		for k, v := range m {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// shardIndex gets the index of the shard responsible for the tile at k.
+func shardIndex(k Loc) uint32 {
+	h := uint32(k[0])*0x9E3779B1 ^ uint32(k[1])*0x85EBCA77
+	h ^= h >> 16
+	return h % syncGridShards
+}
+
+// copyData makes a shallow copy of a data map.
+func copyData(data map[Loc]interface{}) map[Loc]interface{} {
+	c := make(map[Loc]interface{}, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
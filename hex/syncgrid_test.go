@@ -0,0 +1,113 @@
+package hex
+
+import (
+	"sync"
+	"testing"
+)
+
+// compile time check that SyncGrid is a Topology
+var _ Topology = (*SyncGrid)(nil)
+
+func TestSyncGrid_Snapshot(t *testing.T) {
+	grid := NewSyncGrid(NewHexGrid(1, PointyTop))
+	grid.Set(0, 0, 1)
+	grid.Set(1, 0, 2)
+
+	snap := grid.Snapshot()
+	grid.Set(0, 0, 10)
+	grid.Set(1, 0, nil)
+	grid.Set(2, 0, 3)
+
+	tests := []struct {
+		loc       Loc
+		want      interface{}
+		wantOk    bool
+		current   interface{}
+		currentOk bool
+	}{
+		{Loc{0, 0}, 1, true, 10, true},
+		{Loc{1, 0}, 2, true, nil, false},
+		{Loc{2, 0}, nil, false, 3, true},
+	}
+	for _, tt := range tests {
+		if got, ok := snap.Get(tt.loc.CR()); got != tt.want || ok != tt.wantOk {
+			t.Errorf("Snapshot.Get(%v) = %v, %v, want %v, %v", tt.loc, got, ok, tt.want, tt.wantOk)
+		}
+		if got, ok := grid.Get(tt.loc.CR()); got != tt.current || ok != tt.currentOk {
+			t.Errorf("SyncGrid.Get(%v) = %v, %v, want %v, %v", tt.loc, got, ok, tt.current, tt.currentOk)
+		}
+	}
+	if snap.Len() != 2 || grid.Len() != 2 {
+		t.Errorf("Len() = %d (snapshot), %d (grid), want 2, 2", snap.Len(), grid.Len())
+	}
+}
+
+func TestSyncGrid_NewCopiesData(t *testing.T) {
+	geom := NewSquareGrid(1, 0)
+	geom.Set(3, 4, "x")
+	grid := NewSyncGrid(geom)
+	if v, ok := grid.Get(3, 4); !ok || v != "x" {
+		t.Errorf("SyncGrid.Get(3, 4) = %v, %v, want x, true", v, ok)
+	}
+}
+
+// TestSyncGrid_Concurrent is meant to be run with the race detector.
+func TestSyncGrid_Concurrent(t *testing.T) {
+	const (
+		writers = 8
+		readers = 8
+		size    = 20
+		rounds  = 200
+	)
+	grid := NewSyncGrid(NewHexGrid(1, FlatTop))
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				for c := 0; c < size; c++ {
+					if (c+i)%7 == 0 {
+						grid.Set(c, w, nil)
+					} else {
+						grid.Set(c, w, i)
+					}
+				}
+			}
+		}(w)
+	}
+
+	for rd := 0; rd < readers; rd++ {
+		wg.Add(1)
+		go func(rd int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				switch i % 3 {
+				case 0:
+					for c := 0; c < size; c++ {
+						grid.Get(c, rd%writers)
+					}
+				case 1:
+					for k, v := range grid.Map() {
+						if _, ok := v.(int); !ok {
+							t.Errorf("Map()[%v] = %v, not an int", k, v)
+						}
+					}
+				case 2:
+					snap := grid.Snapshot()
+					n := 0
+					snap.Range(func(k Loc, v interface{}) bool {
+						n++
+						return true
+					})
+					if n != snap.Len() {
+						t.Errorf("Snapshot.Range() visited %d tiles, Len() = %d", n, snap.Len())
+					}
+				}
+			}
+		}(rd)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,187 @@
+package kdtree
+
+import "math"
+
+// ApproxOptions tune the approximate nearest neighbor searches, trading
+// accuracy for speed. The zero value makes the searches exact.
+type ApproxOptions struct {
+	// Epsilon skips the parts of the tree which cannot hold a point closer
+	// than the current candidates by more than a factor of (1+Epsilon). The
+	// distance of the i'th neighbor found is then at most (1+Epsilon) times
+	// the distance of the true i'th nearest neighbor.
+	Epsilon float64
+
+	// MaxChecks, if > 0, stops the search after this many points have been
+	// checked, giving no bound on the error.
+	MaxChecks int
+}
+
+// ApproxNearestNeighbor finds a neighbor of searchPt which is nearest or
+// nearly so (see ApproxOptions), and the number of nodes visited to find it.
+// The node is nil if the tree is empty.
+func (t *Tree) ApproxNearestNeighbor(searchPt Point, opts ApproxOptions) (*Node, int) {
+	found, visited := t.ApproxNearestKNeighbors(1, searchPt, opts)
+	if len(found) == 0 {
+		return nil, visited
+	}
+	return found[0].Node, visited
+}
+
+// ApproxNearestKNeighbors finds [0,k] neighbors of searchPt which are the
+// nearest or nearly so (see ApproxOptions), nearest first, and the number of
+// nodes visited to find them.
+//
+// The tree is searched "best bin first": the unexplored branches are kept in
+// a queue, and the one which could hold the nearest points is searched next.
+func (t *Tree) ApproxNearestKNeighbors(k int, searchPt Point, opts ApproxOptions) (found []Neighbor, visited int) {
+	if k <= 0 || t.Root == nil {
+		return nil, 0
+	}
+	m := t.metric()
+	bests := newBestK[Neighbor](k, t.Len())
+	queue := &branchQueue[*Node]{}
+	queue.push(t.Root, 0)
+	for queue.len() > 0 {
+		n, bound := queue.pop()
+		if bound*(1+opts.Epsilon) >= bests.worst() {
+			break // so are all the other branches
+		}
+
+		// go down to a leaf, queueing the branches not taken.
+		for n != nil {
+			if opts.MaxChecks > 0 && visited == opts.MaxChecks {
+				return bests.sorted(), visited
+			}
+			visited++
+			bests.push(Neighbor{n, m.Distance(n.Point, searchPt)})
+
+			near, far := n.Left, n.Right
+			if searchPt[n.Axis] > n.Point[n.Axis] {
+				near, far = far, near
+			}
+			if far != nil {
+				b := math.Max(bound, m.AxisDistance(n.Axis, math.Abs(n.Point[n.Axis]-searchPt[n.Axis])))
+				if b*(1+opts.Epsilon) < bests.worst() {
+					queue.push(far, b)
+				}
+			}
+			n = near
+		}
+	}
+	return bests.sorted(), visited
+}
+
+// ApproxNearestNeighbor finds a neighbor of searchPt which is nearest or
+// nearly so (see ApproxOptions), and the number of points visited to find it.
+// It returns false if the tree is empty.
+func (t *FlatTree) ApproxNearestNeighbor(searchPt Point, opts ApproxOptions) (FlatNeighbor, bool, int) {
+	found, visited := t.ApproxNearestKNeighbors(1, searchPt, opts)
+	if len(found) == 0 {
+		return FlatNeighbor{}, false, visited
+	}
+	return found[0], true, visited
+}
+
+// a subtree of a FlatTree.
+type flatBranch struct {
+	lo, hi, axis int
+}
+
+// ApproxNearestKNeighbors finds [0,k] neighbors of searchPt which are the
+// nearest or nearly so (see ApproxOptions), nearest first, and the number of
+// points visited to find them. See Tree.ApproxNearestKNeighbors.
+func (t *FlatTree) ApproxNearestKNeighbors(k int, searchPt Point, opts ApproxOptions) (found []FlatNeighbor, visited int) {
+	if k <= 0 || t.Len() == 0 {
+		return nil, 0
+	}
+	bests := newBestK[flatNeigh](k, t.Len())
+	queue := &branchQueue[flatBranch]{}
+	queue.push(flatBranch{0, t.Len(), 0}, 0)
+search:
+	for queue.len() > 0 {
+		br, bound := queue.pop()
+		if bound*(1+opts.Epsilon) >= bests.worst() {
+			break
+		}
+
+		for br.lo < br.hi {
+			if opts.MaxChecks > 0 && visited == opts.MaxChecks {
+				break search
+			}
+			visited++
+			mid := br.lo + (br.hi-br.lo)/2
+			p := t.point(mid)
+			bests.push(flatNeigh{mid, t.Metric.Distance(p, searchPt)})
+
+			next := (br.axis + 1) % t.dims
+			near, far := flatBranch{br.lo, mid, next}, flatBranch{mid + 1, br.hi, next}
+			if searchPt[br.axis] > p[br.axis] {
+				near, far = far, near
+			}
+			if far.lo < far.hi {
+				b := math.Max(bound, t.Metric.AxisDistance(br.axis, math.Abs(p[br.axis]-searchPt[br.axis])))
+				if b*(1+opts.Epsilon) < bests.worst() {
+					queue.push(far, b)
+				}
+			}
+			br = near
+		}
+	}
+
+	sorted := bests.sorted()
+	found = make([]FlatNeighbor, len(sorted))
+	for i, b := range sorted {
+		found[i] = t.neighbor(b)
+	}
+	return found, visited
+}
+
+// branchQueue is a min-heap of the branches of a tree left to search, by the
+// lower bound of their distance from the search point.
+type branchQueue[B any] struct {
+	items []queuedBranch[B]
+}
+
+type queuedBranch[B any] struct {
+	branch B
+	bound  float64
+}
+
+func (q *branchQueue[B]) len() int { return len(q.items) }
+
+func (q *branchQueue[B]) push(b B, bound float64) {
+	q.items = append(q.items, queuedBranch[B]{b, bound})
+	s := q.items
+	for i := len(s) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if s[parent].bound <= s[i].bound {
+			break
+		}
+		s[parent], s[i] = s[i], s[parent]
+		i = parent
+	}
+}
+
+func (q *branchQueue[B]) pop() (B, float64) {
+	s := q.items
+	top := s[0]
+	n := len(s) - 1
+	s[0] = s[n]
+	q.items = s[:n]
+
+	for i := 0; ; {
+		smallest := i
+		if l := 2*i + 1; l < n && s[l].bound < s[smallest].bound {
+			smallest = l
+		}
+		if r := 2*i + 2; r < n && s[r].bound < s[smallest].bound {
+			smallest = r
+		}
+		if smallest == i {
+			break
+		}
+		s[i], s[smallest] = s[smallest], s[i]
+		i = smallest
+	}
+	return top.branch, top.bound
+}
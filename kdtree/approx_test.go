@@ -0,0 +1,132 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// approxTree is the part of Tree and FlatTree used by the approximate search
+// tests, with the results as distances.
+type approxTree func(k int, q Point, opts ApproxOptions) ([]float64, int)
+
+func approxTrees(pts []Point) map[string]approxTree {
+	tree := NewTree(append([]Point(nil), pts...), Euclidean)
+	flat := NewFlatTree(pts, Euclidean, 1)
+	return map[string]approxTree{
+		"tree": func(k int, q Point, opts ApproxOptions) ([]float64, int) {
+			found, visited := tree.ApproxNearestKNeighbors(k, q, opts)
+			dists := []float64{}
+			for _, n := range found {
+				dists = append(dists, n.Dist)
+			}
+			return dists, visited
+		},
+		"flat": func(k int, q Point, opts ApproxOptions) ([]float64, int) {
+			found, visited := flat.ApproxNearestKNeighbors(k, q, opts)
+			dists := []float64{}
+			for _, n := range found {
+				dists = append(dists, n.Dist)
+			}
+			return dists, visited
+		},
+	}
+}
+
+func TestApproxNearestKNeighbors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{2, 8, 16} {
+		pts := randomPoints(rng, 2000, dims)
+		for name, search := range approxTrees(pts) {
+			t.Run(fmt.Sprintf("%s %dd", name, dims), func(t *testing.T) {
+				for i := 0; i < 50; i++ {
+					q := randomPoint(rng, dims, 0, 100)
+					want := bruteForce(pts, q, Euclidean)[:10]
+
+					// exact
+					got, exactVisited := search(10, q, ApproxOptions{})
+					for j := range want {
+						if got[j] != want[j] {
+							t.Fatalf("exact [%d] at %v, want %v", j, got[j], want[j])
+						}
+					}
+
+					// within the error bound, visiting no more nodes.
+					got, visited := search(10, q, ApproxOptions{Epsilon: 0.5})
+					for j := range want {
+						if got[j] > 1.5*want[j] {
+							t.Fatalf("epsilon 0.5 [%d] at %v, want at most %v", j, got[j], 1.5*want[j])
+						}
+					}
+					if visited > exactVisited {
+						t.Fatalf("epsilon 0.5 visited %d nodes, more than the exact %d", visited, exactVisited)
+					}
+
+					got, visited = search(10, q, ApproxOptions{MaxChecks: 50})
+					if visited > 50 || len(got) != 10 {
+						t.Fatalf("max checks 50 visited %d nodes, found %d", visited, len(got))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestApproxNearestNeighbor(t *testing.T) {
+	pts := randomPoints(rand.New(rand.NewSource(1)), 100, 3)
+	q := Point{50, 50, 50}
+	want := bruteForce(pts, q, Euclidean)[0]
+
+	tree := NewTree(append([]Point(nil), pts...), Euclidean)
+	if n, visited := tree.ApproxNearestNeighbor(q, ApproxOptions{}); Euclidean.Distance(n.Point, q) != want || visited == 0 {
+		t.Errorf("Tree.ApproxNearestNeighbor() = %v after %d visits, want distance %v", n.Point, visited, want)
+	}
+	flat := NewFlatTree(pts, Euclidean, 1)
+	if n, ok, _ := flat.ApproxNearestNeighbor(q, ApproxOptions{}); !ok || n.Dist != want {
+		t.Errorf("FlatTree.ApproxNearestNeighbor() = %v, want distance %v", n, want)
+	}
+
+	if n, visited := (&Tree{}).ApproxNearestNeighbor(q, ApproxOptions{}); n != nil || visited != 0 {
+		t.Errorf("ApproxNearestNeighbor() in empty tree = %v, %d", n, visited)
+	}
+	if _, ok, _ := NewFlatTree(nil, nil, 1).ApproxNearestNeighbor(q, ApproxOptions{}); ok {
+		t.Errorf("ApproxNearestNeighbor() in empty flat tree found a point")
+	}
+}
+
+// recall gets the fraction of the true 10 nearest neighbors found with opts,
+// and the average number of nodes visited.
+func recall(tree *FlatTree, pts, qs []Point, opts ApproxOptions) (float64, float64) {
+	hits, visits := 0, 0
+	for _, q := range qs {
+		want := bruteForce(pts, q, Euclidean)[9]
+		found, visited := tree.ApproxNearestKNeighbors(10, q, opts)
+		for _, n := range found {
+			if n.Dist <= want {
+				hits++
+			}
+		}
+		visits += visited
+	}
+	return float64(hits) / float64(10*len(qs)), float64(visits) / float64(len(qs))
+}
+
+func TestApprox_Recall(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := randomPoints(rng, 20000, 16)
+	qs := randomPoints(rng, 20, 16)
+	tree := NewFlatTree(pts, Euclidean, 1)
+
+	prevRecall, prevVisits := 0.0, 0.0
+	for _, checks := range []int{100, 1000, 10000, 0} {
+		r, v := recall(tree, pts, qs, ApproxOptions{MaxChecks: checks})
+		t.Logf("max checks %d: recall %.3f, %.0f visits", checks, r, v)
+		if r < prevRecall || v < prevVisits {
+			t.Errorf("max checks %d: recall %.3f and %.0f visits, less than with fewer checks", checks, r, v)
+		}
+		prevRecall, prevVisits = r, v
+	}
+	if prevRecall != 1 {
+		t.Errorf("exact search has recall %v", prevRecall)
+	}
+}
@@ -0,0 +1,213 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// minimum number of nodes in a subtree for BuildTreeParallel to build its
+// children concurrently.
+const minParallelBuild = 1 << 14
+
+// BuildTree makes the kd tree and returns its root node, or nil if there are
+// no items. The items are not reordered, and the points are not copied. All
+// items must have the same number of dimensions. The Range of the root is the
+// bounding box of the items.
+func BuildTree(items []Point) (root *Node) {
+	return BuildTreeParallel(items, 1)
+}
+
+// BuildTreeParallel is BuildTree, with the work split among up to workers
+// goroutines for large trees.
+//
+// The items are sorted once on each axis, and each level of the tree
+// partitions those orders around its medians, so the build takes O(kn log n)
+// time for n points of k dimensions.
+func BuildTreeParallel(items []Point, workers int) (root *Node) {
+	if len(items) == 0 {
+		return nil
+	}
+	dims := len(items[0])
+	if dims == 0 {
+		panic("kdtree: points have no dimensions")
+	}
+	rng := make([]mgl64.Vec2, dims)
+	for axis := range rng {
+		rng[axis] = mgl64.Vec2{items[0][axis], items[0][axis]}
+	}
+	for _, p := range items[1:] {
+		if len(p) != dims {
+			panic("kdtree: points have different dimensions")
+		}
+		for axis := range rng {
+			rng[axis][0] = math.Min(rng[axis][0], p[axis])
+			rng[axis][1] = math.Max(rng[axis][1], p[axis])
+		}
+	}
+
+	// allocate all the nodes at once.
+	slab := make([]Node, len(items))
+	nodes := make([]*Node, len(items))
+	for i := range slab {
+		slab[i].Point = items[i]
+		slab[i].Index = i
+		nodes[i] = &slab[i]
+	}
+	b := newBuilder(len(items), dims, func(i int) Point { return items[i] }, workers)
+	b.nodes = nodes
+	return b.build(0, len(nodes), 0, nil, rng, workers)
+}
+
+// builder arranges points into a balanced tree.
+type builder struct {
+	nodes   []*Node     // the node of each point, for build
+	coords  [][]float64 // for each axis, the points' coordinates
+	sorted  [][]int32   // for each axis, the point indices in order on the axis
+	scratch []int32
+	left    []bool // for each point, if it goes left of the split being made
+}
+
+// newBuilder sorts the n points on each axis, using up to workers goroutines.
+func newBuilder(n, dims int, point func(i int) Point, workers int) *builder {
+	b := &builder{
+		coords:  make([][]float64, dims),
+		sorted:  make([][]int32, dims),
+		scratch: make([]int32, n),
+		left:    make([]bool, n),
+	}
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, maxInt(workers, 1))
+	for axis := range b.sorted {
+		c := make([]float64, n)
+		s := make([]int32, n)
+		for i := range s {
+			c[i] = point(i)[axis]
+			s[i] = int32(i)
+		}
+		b.coords[axis], b.sorted[axis] = c, s
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			sort.Sort(axisOrder{s, c})
+			<-sem
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	return b
+}
+
+// axisOrder sorts node indices by their coordinates on an axis, breaking ties
+// by index so that every axis has a strict order.
+type axisOrder struct {
+	idx    []int32
+	coords []float64
+}
+
+func (o axisOrder) Len() int      { return len(o.idx) }
+func (o axisOrder) Swap(i, j int) { o.idx[i], o.idx[j] = o.idx[j], o.idx[i] }
+func (o axisOrder) Less(i, j int) bool {
+	a, b := o.idx[i], o.idx[j]
+	va, vb := o.coords[a], o.coords[b]
+	return va < vb || va == vb && a < b
+}
+
+// partition finds the median point of [lo,hi) of the sorted orders on axis,
+// and partitions the orders on the other axes the same way: points before the
+// median to [lo,median), and after it to [median+1,hi).
+func (b *builder) partition(lo, hi, axis int) (median int, split int32) {
+	s := b.sorted[axis]
+	median = lo + (hi-lo)/2
+	split = s[median]
+
+	for i := lo; i < hi; i++ {
+		b.left[s[i]] = i < median
+	}
+	for a, o := range b.sorted {
+		if a == axis {
+			continue
+		}
+		l, r := lo, lo
+		for _, i := range o[lo:hi] {
+			switch {
+			case b.left[i]:
+				o[l] = i
+				l++
+			case i != split:
+				b.scratch[r] = i
+				r++
+			}
+		}
+		copy(o[median+1:hi], b.scratch[lo:r])
+	}
+	return
+}
+
+// build links the nodes at [lo,hi) of the sorted orders into a subtree
+// splitting on axis, and returns its root.
+func (b *builder) build(lo, hi, axis int, parent *Node, rng []mgl64.Vec2, workers int) *Node {
+	if lo == hi {
+		return nil
+	}
+
+	median, split := b.partition(lo, hi, axis)
+	n := b.nodes[split]
+	n.Axis, n.Range, n.Parent, n.size = axis, rng, parent, hi-lo
+
+	// create the "ranges" for the left and right children.
+	// the ranges are used for plotting.
+	l, r := splitRange(rng, axis, n.Point[axis])
+
+	next := (axis + 1) % len(b.sorted)
+	b.split(lo, hi, workers,
+		func(w int) { n.Left = b.build(lo, median, next, n, l, w) },
+		func(w int) { n.Right = b.build(median+1, hi, next, n, r, w) })
+	return n
+}
+
+// order puts the indices of the points at [lo,hi) of the sorted orders into
+// out[lo:hi] in the implicit layout of FlatTree: the subtree splitting on
+// axis has its root in the middle, and its children on either side.
+func (b *builder) order(lo, hi, axis int, out []int32, workers int) {
+	if lo == hi {
+		return
+	}
+	median, split := b.partition(lo, hi, axis)
+	out[median] = split
+
+	next := (axis + 1) % len(b.sorted)
+	b.split(lo, hi, workers,
+		func(w int) { b.order(lo, median, next, out, w) },
+		func(w int) { b.order(median+1, hi, next, out, w) })
+}
+
+// split runs left and right, which build the 2 halves of [lo,hi),
+// concurrently if workers > 1 and there is enough work.
+func (b *builder) split(lo, hi, workers int, left, right func(workers int)) {
+	if workers <= 1 || hi-lo < minParallelBuild {
+		left(1)
+		right(1)
+		return
+	}
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		left(workers / 2)
+		wg.Done()
+	}()
+	right(workers - workers/2)
+	wg.Wait()
+}
+
+// splitRange splits rng in 2 on axis at v.
+func splitRange(rng []mgl64.Vec2, axis int, v float64) (l, r []mgl64.Vec2) {
+	l = append([]mgl64.Vec2(nil), rng...)
+	r = append([]mgl64.Vec2(nil), rng...)
+	l[axis][1] = v
+	r[axis][0] = v
+	return
+}
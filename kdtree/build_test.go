@@ -0,0 +1,112 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// sortBuildTree is the original builder, which sorts the items at every
+// level, kept to compare with BuildTree.
+func sortBuildTree(items []Point, depth, dims int, parent *Node, rng []mgl64.Vec2) (node *Node) {
+	if len(items) == 0 {
+		return nil
+	}
+
+	// ascending sort items by axis
+	axis := depth % dims // 0=x, 1=y, 2=z, ...
+	sort.Slice(items, func(i, j int) bool {
+		return items[i][axis] < items[j][axis]
+	})
+
+	// create node
+	median := len(items) / 2
+	n := &Node{
+		Axis:   axis,
+		Range:  rng,
+		Point:  items[median],
+		Parent: parent,
+		size:   len(items)}
+
+	l, r := splitRange(rng, axis, n.Point[axis])
+	n.Left = sortBuildTree(items[:median], depth+1, dims, n, l)
+	n.Right = sortBuildTree(items[median+1:], depth+1, dims, n, r)
+
+	return n
+}
+
+func TestBuildTreeParallel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{1, 2, 3, 8} {
+		t.Run(fmt.Sprintf("%dd", dims), func(t *testing.T) {
+			// few distinct coordinates, so there are many ties.
+			pts := make([]Point, 3*minParallelBuild)
+			for i := range pts {
+				pts[i] = make(Point, dims)
+				for j := range pts[i] {
+					pts[i][j] = float64(rng.Intn(50))
+				}
+			}
+			orig := append([]Point(nil), pts...)
+
+			serial := &Tree{Root: BuildTree(pts)}
+			parallel := &Tree{Root: BuildTreeParallel(pts, 4)}
+			for i := range pts {
+				if &pts[i][0] != &orig[i][0] {
+					t.Fatalf("BuildTree() reordered the items")
+				}
+			}
+			checkTree(t, serial)
+			checkTree(t, parallel)
+			if h, want := height(serial.Root), 16; h != want {
+				t.Errorf("tree of %d nodes has height %d, want %d", len(pts), h, want)
+			}
+
+			// both builders make the same tree.
+			var a, b []Point
+			PreOrderTraversal(serial.Root, func(n *Node) { a = append(a, n.Point) })
+			PreOrderTraversal(parallel.Root, func(n *Node) { b = append(b, n.Point) })
+			for i := range a {
+				if &a[i][0] != &b[i][0] {
+					t.Fatalf("BuildTreeParallel() made a different tree")
+				}
+			}
+		})
+	}
+}
+
+func benchmarkBuild(b *testing.B, build func([]Point)) {
+	for _, n := range []int{1e5, 1e6, 1e7} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			pts := randomPoints(rand.New(rand.NewSource(1)), n, 3)
+			work := make([]Point, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// a fresh copy each time, as sortBuildTree reorders it.
+				b.StopTimer()
+				copy(work, pts)
+				b.StartTimer()
+				build(work)
+			}
+		})
+	}
+}
+
+func BenchmarkBuildTree(b *testing.B) {
+	benchmarkBuild(b, func(pts []Point) { BuildTree(pts) })
+}
+
+func BenchmarkBuildTreeParallel(b *testing.B) {
+	benchmarkBuild(b, func(pts []Point) { BuildTreeParallel(pts, runtime.GOMAXPROCS(0)) })
+}
+
+func BenchmarkBuildTree_Sort(b *testing.B) {
+	benchmarkBuild(b, func(pts []Point) {
+		rng := make([]mgl64.Vec2, len(pts[0]))
+		sortBuildTree(pts, 0, len(pts[0]), nil, rng)
+	})
+}
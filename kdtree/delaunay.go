@@ -0,0 +1,259 @@
+package kdtree
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Triangle is a triangle of a Delaunay triangulation, by the indices of its
+// corners in the points, counterclockwise (with the y axis up).
+type Triangle [3]int
+
+// Delaunay triangulates the points so that no point is inside the
+// circumcircle of any triangle, with the Bowyer-Watson algorithm. Points equal
+// to an earlier point are skipped. There are no triangles if there are less
+// than 3 distinct points or they are all on a line.
+//
+// Each point is inserted by removing the triangles whose circumcircles hold
+// it and joining it to the edges of the hole, which takes O(n^2) in all.
+// Outside the hull are "ghost" triangles, joining each hull edge to a vertex
+// at infinity, whose circumcircles are the open half-planes beyond their
+// edges. The geometric tests are exact (see orient and inCircle), so points
+// on a circle or close to a line are handled consistently.
+func Delaunay(points []mgl64.Vec2) []Triangle {
+	// the distinct points, in order.
+	seen := make(map[mgl64.Vec2]bool, len(points))
+	order := make([]int, 0, len(points))
+	for i, p := range points {
+		if !seen[p] {
+			seen[p] = true
+			order = append(order, i)
+		}
+	}
+
+	// start with the first 2 points and the first point off their line.
+	if len(order) < 3 {
+		return nil
+	}
+	k := 2
+	for k < len(order) && orient(points[order[0]], points[order[1]], points[order[k]]) == 0 {
+		k++
+	}
+	if k == len(order) {
+		return nil
+	}
+	a, b, c := order[0], order[1], order[k]
+	if orient(points[a], points[b], points[c]) < 0 {
+		b, c = c, b
+	}
+	tris := []Triangle{{a, b, c}, {b, a, infinite}, {c, b, infinite}, {a, c, infinite}}
+	order = append(order[2:k], order[k+1:]...)
+
+	for _, i := range order {
+		p := points[i]
+
+		// find the hole, and its edges: the edges of only one removed triangle.
+		// the edges go counterclockwise around the hole.
+		edges := map[[2]int]bool{}
+		kept := tris[:0]
+		for _, t := range tris {
+			if !conflicts(points, t, p) {
+				kept = append(kept, t)
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				e := [2]int{t[k], t[(k+1)%3]}
+				if rev := [2]int{e[1], e[0]}; edges[rev] {
+					delete(edges, rev)
+				} else {
+					edges[e] = true
+				}
+			}
+		}
+		tris = kept
+		for e := range edges {
+			// the vertex at infinity stays last in ghost triangles.
+			switch {
+			case e[0] == infinite:
+				tris = append(tris, Triangle{e[1], i, infinite})
+			case e[1] == infinite:
+				tris = append(tris, Triangle{i, e[0], infinite})
+			default:
+				tris = append(tris, Triangle{e[0], e[1], i})
+			}
+		}
+	}
+
+	// drop the ghost triangles.
+	kept := tris[:0]
+	for _, t := range tris {
+		if t[2] != infinite {
+			kept = append(kept, t)
+		}
+	}
+	// the hole's edges come out of a map, so sort for a stable result.
+	sort.Slice(kept, func(i, j int) bool {
+		a, b := kept[i], kept[j]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return false
+	})
+	return kept
+}
+
+// infinite is the index of the vertex at infinity in ghost triangles, which
+// is always their last corner.
+const infinite = -1
+
+// conflicts tests if p is strictly inside the circumcircle of t. For a ghost
+// triangle that is the open half-plane beyond its edge, plus the inside of the
+// edge itself.
+func conflicts(points []mgl64.Vec2, t Triangle, p mgl64.Vec2) bool {
+	if t[2] != infinite {
+		return inCircle(points[t[0]], points[t[1]], points[t[2]], p) > 0
+	}
+	a, b := points[t[0]], points[t[1]]
+	switch o := orient(a, b, p); {
+	case o != 0:
+		return o > 0
+	case a.X() != b.X():
+		return math.Min(a.X(), b.X()) < p.X() && p.X() < math.Max(a.X(), b.X())
+	default:
+		return math.Min(a.Y(), b.Y()) < p.Y() && p.Y() < math.Max(a.Y(), b.Y())
+	}
+}
+
+// VoronoiCell is the Euclidean Voronoi cell of a point, clipped to a box.
+type VoronoiCell struct {
+	// Polygon is the corners of the cell, counterclockwise (with the y axis
+	// up). It is empty if the cell is outside the box, or the point is equal
+	// to an earlier point.
+	Polygon []mgl64.Vec2
+	// Neighbors are the indices of the points whose cells share an edge with
+	// this one, in the order of the edges.
+	Neighbors []int
+}
+
+// VoronoiCells gets the Euclidean Voronoi cell of each point, clipped to the
+// box from min to max. The cell of points[i] is cells[i]. Each cell is made
+// by clipping the box with the half-planes nearer to the point than to each
+// of its neighbors in the Delaunay triangulation.
+func VoronoiCells(points []mgl64.Vec2, min, max mgl64.Vec2) (cells []VoronoiCell, err error) {
+	if !(min.X() <= max.X() && min.Y() <= max.Y()) {
+		return nil, errors.New("kdtree: voronoi box min must not be more than max")
+	}
+	// which points are neighbors. if there are no triangles, the points are
+	// on a line, and neighbors along it.
+	adjacent := make([]map[int]bool, len(points))
+	for i := range adjacent {
+		adjacent[i] = map[int]bool{}
+	}
+	link := func(i, j int) {
+		adjacent[i][j] = true
+		adjacent[j][i] = true
+	}
+	tris := Delaunay(points)
+	for _, t := range tris {
+		link(t[0], t[1])
+		link(t[1], t[2])
+		link(t[2], t[0])
+	}
+	first := make(map[mgl64.Vec2]int, len(points))
+	for i, p := range points {
+		if _, ok := first[p]; !ok {
+			first[p] = i
+		}
+	}
+	if len(tris) == 0 {
+		order := make([]int, 0, len(first))
+		for _, i := range first {
+			order = append(order, i)
+		}
+		sort.Slice(order, func(i, j int) bool {
+			a, b := points[order[i]], points[order[j]]
+			return a.X() < b.X() || a.X() == b.X() && a.Y() < b.Y()
+		})
+		for k := 1; k < len(order); k++ {
+			link(order[k-1], order[k])
+		}
+	}
+
+	// corners closer than this are taken to be the same, and edges shorter
+	// than it are where cells meet at a corner.
+	tiny := 1e-9 * math.Max(math.Max(max.X()-min.X(), max.Y()-min.Y()), 1)
+	cells = make([]VoronoiCell, len(points))
+	for i, p := range points {
+		if first[p] != i {
+			continue
+		}
+		poly := clipPolygon{
+			corners: []mgl64.Vec2{min, {max.X(), min.Y()}, max, {min.X(), max.Y()}},
+			edges:   []int{-1, -1, -1, -1},
+		}
+		// in order, for the same polygon every time.
+		neighbors := make([]int, 0, len(adjacent[i]))
+		for j := range adjacent[i] {
+			neighbors = append(neighbors, j)
+		}
+		sort.Ints(neighbors)
+		for _, j := range neighbors {
+			poly = poly.clip(p, points[j], j, tiny)
+		}
+		cells[i].Polygon = poly.corners
+		for k, j := range poly.edges {
+			next := poly.corners[(k+1)%len(poly.corners)]
+			if j >= 0 && next.Sub(poly.corners[k]).Len() > tiny {
+				cells[i].Neighbors = append(cells[i].Neighbors, j)
+			}
+		}
+	}
+	return cells, nil
+}
+
+// clipPolygon is a convex polygon being clipped into a Voronoi cell.
+type clipPolygon struct {
+	corners []mgl64.Vec2
+	edges   []int // the point whose bisector the edge from each corner is on, or -1
+}
+
+// clip cuts away the part of the polygon nearer to q than to p. The new edge
+// on their bisector is labeled j. Corners within tol of the bisector are taken
+// to be on it, so that many bisectors through one point (as for points on a
+// circle) don't leave slivers of rounding error.
+func (poly clipPolygon) clip(p, q mgl64.Vec2, j int, tol float64) clipPolygon {
+	mid, dir := p.Add(q).Mul(0.5), q.Sub(p)
+	eps := tol * dir.Len()
+	side := func(v mgl64.Vec2) float64 { return v.Sub(mid).Dot(dir) } // <= eps is kept
+
+	var out clipPolygon
+	n := len(poly.corners)
+	for k, a := range poly.corners {
+		b := poly.corners[(k+1)%n]
+		sa, sb := side(a), side(b)
+		cross := func() mgl64.Vec2 { return a.Add(b.Sub(a).Mul(sa / (sa - sb))) }
+		switch {
+		case sa <= eps && sb <= eps:
+			out.corners = append(out.corners, a)
+			out.edges = append(out.edges, poly.edges[k])
+		case sa < -eps:
+			// leaving: keep a, then follow the bisector from the crossing.
+			out.corners = append(out.corners, a, cross())
+			out.edges = append(out.edges, poly.edges[k], j)
+		case sa <= eps:
+			// leaving from the bisector: follow it from a.
+			out.corners = append(out.corners, a)
+			out.edges = append(out.edges, j)
+		case sb < -eps:
+			// entering: the rest of the edge is kept.
+			out.corners = append(out.corners, cross())
+			out.edges = append(out.edges, poly.edges[k])
+		}
+	}
+	return out
+}
@@ -0,0 +1,365 @@
+package kdtree
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// random2d makes n random points in [0,100)^2.
+func random2d(rng *rand.Rand, n int) []mgl64.Vec2 {
+	points := make([]mgl64.Vec2, n)
+	for i, p := range randomPoints(rng, n, 2) {
+		points[i] = p.Vec2()
+	}
+	return points
+}
+
+// twice the signed area of the triangle, positive if counterclockwise.
+func cross(a, b, c mgl64.Vec2) float64 {
+	return (b.X()-a.X())*(c.Y()-a.Y()) - (b.Y()-a.Y())*(c.X()-a.X())
+}
+
+// area of a counterclockwise polygon.
+func polygonArea(poly []mgl64.Vec2) float64 {
+	a := 0.0
+	for k := 1; k+1 < len(poly); k++ {
+		a += cross(poly[0], poly[k], poly[k+1]) / 2
+	}
+	return a
+}
+
+// convex hull of the points, counterclockwise.
+func convexHull(points []mgl64.Vec2) []mgl64.Vec2 {
+	pts := append([]mgl64.Vec2(nil), points...)
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i].X() < pts[j].X() || pts[i].X() == pts[j].X() && pts[i].Y() < pts[j].Y()
+	})
+	var hull []mgl64.Vec2
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range pts {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return hull
+}
+
+// arc makes n points on the circle around center with radius r, from angle
+// from to angle to (excluded).
+func arc(n int, center mgl64.Vec2, r, from, to float64) []mgl64.Vec2 {
+	points := make([]mgl64.Vec2, n)
+	for i := range points {
+		sin, cos := math.Sincos(from + (to-from)*float64(i)/float64(n))
+		points[i] = center.Add(mgl64.Vec2{r * cos, r * sin})
+	}
+	return points
+}
+
+// nearLine makes n points on the line y=y0, x=x0, x0+dx..., and one more
+// point just off the line in the middle.
+func nearLine(n int, x0, dx, y0 float64) []mgl64.Vec2 {
+	points := make([]mgl64.Vec2, n+1)
+	for i := 0; i < n; i++ {
+		points[i] = mgl64.Vec2{x0 + dx*float64(i), y0}
+	}
+	points[n] = mgl64.Vec2{x0 + dx*(float64(n)/2-0.5), y0 + 1e-4}
+	return points
+}
+
+// thin makes n random points in the box from min to max.
+func thin(rng *rand.Rand, n int, min, max mgl64.Vec2) []mgl64.Vec2 {
+	points := make([]mgl64.Vec2, n)
+	for i := range points {
+		points[i] = mgl64.Vec2{
+			min.X() + rng.Float64()*(max.X()-min.X()),
+			min.Y() + rng.Float64()*(max.Y()-min.Y()),
+		}
+	}
+	return points
+}
+
+// lattice makes the points of an n x n grid with the given spacing.
+func lattice(n int, origin mgl64.Vec2, spacing float64) []mgl64.Vec2 {
+	var points []mgl64.Vec2
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			points = append(points, origin.Add(mgl64.Vec2{float64(i), float64(j)}.Mul(spacing)))
+		}
+	}
+	return points
+}
+
+func TestDelaunay(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name   string
+		points []mgl64.Vec2
+	}{
+		{"random 3", random2d(rng, 3)},
+		{"random 4", random2d(rng, 4)},
+		{"random 10", random2d(rng, 10)},
+		{"random 100", random2d(rng, 100)},
+		{"random 500", random2d(rng, 500)},
+		{"regular polygon", arc(64, mgl64.Vec2{}, 1, 0, 2*math.Pi)},
+		{"arc", arc(60, mgl64.Vec2{}, 1, 0, math.Pi/3)},
+		{"near line", nearLine(50, 0, 1, 0)},
+		{"wide", thin(rng, 300, mgl64.Vec2{0, 0}, mgl64.Vec2{1e6, 1})},
+		{"lattice", lattice(10, mgl64.Vec2{}, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := tt.points
+			tris := Delaunay(points)
+
+			area := 0.0
+			edges := map[[2]int]bool{}
+			for _, tri := range tris {
+				a, b, c := points[tri[0]], points[tri[1]], points[tri[2]]
+				if orient(a, b, c) <= 0 {
+					t.Fatalf("triangle %v is not counterclockwise", tri)
+				}
+				area += cross(a, b, c) / 2
+				for k := 0; k < 3; k++ {
+					e := [2]int{tri[k], tri[(k+1)%3]}
+					if edges[e] {
+						t.Fatalf("edge %v is in 2 triangles the same way, so they overlap", e)
+					}
+					edges[e] = true
+				}
+
+				for i, p := range points {
+					if inCircle(a, b, c, p) > 0 {
+						t.Fatalf("point %d is inside the circumcircle of %v", i, tri)
+					}
+				}
+			}
+
+			// the edges without a twin go around the hull, through every point
+			// on it, and a triangulation of n points with h on the hull has
+			// 2n-2-h triangles.
+			hull := 0
+			for e := range edges {
+				if !edges[[2]int{e[1], e[0]}] {
+					hull++
+				}
+			}
+			if want := 2*len(points) - 2 - hull; len(tris) != want {
+				t.Errorf("%d triangles, want %d", len(tris), want)
+			}
+			if want := polygonArea(convexHull(points)); math.Abs(area-want) > 1e-9*want {
+				t.Errorf("triangles cover %g, want the hull's %g", area, want)
+			}
+		})
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	// nearly degenerate points, where the float64 determinants are mostly
+	// rounding error. swapping 2 points must flip the sign.
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		a := mgl64.Vec2{0.5 + rng.Float64()*1e-12, 0.5 + rng.Float64()*1e-12}
+		b := mgl64.Vec2{12, 12}
+		c := mgl64.Vec2{24, 24}
+		if o1, o2 := orient(a, b, c), orient(b, a, c); o1 != -o2 || o1 != orient(b, c, a) {
+			t.Fatalf("orient(%v, %v, %v) is not consistent: %d, %d", a, b, c, o1, o2)
+		}
+
+		pts := arc(4, mgl64.Vec2{0.1, 0.3}, 0.7, rng.Float64(), rng.Float64()+2*math.Pi)
+		pts[3] = pts[3].Add(mgl64.Vec2{rng.Float64() * 1e-15, 0})
+		p, q, r, d := pts[0], pts[1], pts[2], pts[3]
+		if o1, o2 := inCircle(p, q, r, d), inCircle(q, p, r, d); o1 != -o2 || o1 != inCircle(q, r, p, d) {
+			t.Fatalf("inCircle(%v, %v, %v, %v) is not consistent: %d, %d", p, q, r, d, o1, o2)
+		}
+	}
+
+	if got := orient(mgl64.Vec2{0, 0}, mgl64.Vec2{1, 1}, mgl64.Vec2{3, 3}); got != 0 {
+		t.Errorf("orient of points on a line = %d, want 0", got)
+	}
+	sq := []mgl64.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	if got := inCircle(sq[0], sq[1], sq[2], sq[3]); got != 0 {
+		t.Errorf("inCircle of the corners of a square = %d, want 0", got)
+	}
+}
+
+func TestDelaunay_Degenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []mgl64.Vec2
+		want   []Triangle
+	}{
+		{"none", nil, nil},
+		{"two", []mgl64.Vec2{{0, 0}, {1, 1}}, nil},
+		{"line", []mgl64.Vec2{{0, 0}, {1, 1}, {3, 3}, {2, 2}}, nil},
+		{"same", []mgl64.Vec2{{1, 1}, {1, 1}, {1, 1}}, nil},
+		{"triangle", []mgl64.Vec2{{0, 0}, {0, 1}, {1, 0}}, []Triangle{{0, 2, 1}}},
+		{"duplicates", []mgl64.Vec2{{0, 0}, {0, 1}, {0, 0}, {1, 0}, {0, 1}}, []Triangle{{0, 3, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Delaunay(tt.points)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !sameTriangle(got[i], tt.want[i]) {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// 4 points on a circle have 2 triangulations, either will do.
+	if got := Delaunay([]mgl64.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}}); len(got) != 2 {
+		t.Errorf("square: got %v, want 2 triangles", got)
+	}
+}
+
+// sameTriangle tests if a and b have the same corners in the same cyclic
+// order.
+func sameTriangle(a, b Triangle) bool {
+	for k := 0; k < 3; k++ {
+		if a == (Triangle{b[k], b[(k+1)%3], b[(k+2)%3]}) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestVoronoiCells checks the cells against the raster diagram: every pixel
+// must be in the cell of the point whose color it has.
+func TestVoronoiCells(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name   string
+		points []mgl64.Vec2
+	}{
+		{"random 1", random2d(rng, 1)},
+		{"random 2", random2d(rng, 2)},
+		{"random 3", random2d(rng, 3)},
+		{"random 10", random2d(rng, 10)},
+		{"random 60", random2d(rng, 60)},
+		{"regular polygon", arc(64, mgl64.Vec2{50, 50}, 40, 0, 2*math.Pi)},
+		{"arc", arc(60, mgl64.Vec2{50, -50}, 110, 7*math.Pi/18, 11*math.Pi/18)},
+		{"near line", nearLine(50, 1, 2, 50)},
+		{"thin", thin(rng, 300, mgl64.Vec2{0, 50}, mgl64.Vec2{100, 50 + 1e-4})},
+		{"lattice", lattice(10, mgl64.Vec2{5, 5}, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := tt.points
+			cells, err := VoronoiCells(points, mgl64.Vec2{0, 0}, mgl64.Vec2{100, 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			area := 0.0
+			for i, c := range cells {
+				area += polygonArea(c.Polygon)
+				if !inPolygon(c.Polygon, points[i]) {
+					t.Errorf("point %d is not in its cell", i)
+				}
+				for _, j := range c.Neighbors {
+					if !containsInt(cells[j].Neighbors, i) {
+						t.Errorf("%d neighbors %d but not the other way", i, j)
+					}
+				}
+			}
+			if math.Abs(area-100*100) > 1e-6 {
+				t.Errorf("cells cover %g, want the box's %g", area, 100.0*100)
+			}
+
+			// every pixel must be in the cell of the point whose color it has.
+			items := make([]Item[color.RGBA], len(points))
+			for i, p := range points {
+				items[i] = Item[color.RGBA]{Vec2Point(p), color.RGBA{uint8(i), uint8(i >> 8), 0, 255}}
+			}
+			img := renderVoronoi(NewIndex(items, Euclidean, 1), 100, 100, 0, color.RGBA{}, 1)
+			for y := 0; y < 100; y++ {
+				for x := 0; x < 100; x++ {
+					c := img.RGBAAt(x, y)
+					i := int(c.R) | int(c.G)<<8
+					if !inPolygon(cells[i].Polygon, mgl64.Vec2{float64(x), float64(y)}) {
+						t.Fatalf("pixel (%d,%d) of point %d is not in its cell %v", x, y, i, cells[i].Polygon)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestVoronoiCells_Adjacency(t *testing.T) {
+	// a row of 3 points: the middle one neighbors both ends.
+	cells, err := VoronoiCells([]mgl64.Vec2{{1, 5}, {5, 5}, {9, 5}}, mgl64.Vec2{0, 0}, mgl64.Vec2{10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{1}, {0, 2}, {1}}
+	for i, c := range cells {
+		got := append([]int(nil), c.Neighbors...)
+		sort.Ints(got)
+		if len(got) != len(want[i]) {
+			t.Fatalf("cell %d neighbors %v, want %v", i, got, want[i])
+		}
+		for k := range got {
+			if got[k] != want[i][k] {
+				t.Fatalf("cell %d neighbors %v, want %v", i, got, want[i])
+			}
+		}
+	}
+	if a := polygonArea(cells[1].Polygon); a != 40 {
+		t.Errorf("middle cell area %g, want 40", a)
+	}
+
+	// the corners of a square meet only at its center, so diagonal points
+	// are not neighbors.
+	cells, err = VoronoiCells([]mgl64.Vec2{{2, 2}, {8, 2}, {8, 8}, {2, 8}}, mgl64.Vec2{0, 0}, mgl64.Vec2{10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range cells {
+		if len(c.Neighbors) != 2 || containsInt(c.Neighbors, (i+2)%4) {
+			t.Errorf("cell %d neighbors %v", i, c.Neighbors)
+		}
+	}
+
+	if _, err := VoronoiCells(nil, mgl64.Vec2{1, 0}, mgl64.Vec2{0, 1}); err == nil {
+		t.Error("no error for an empty box")
+	}
+}
+
+// inPolygon tests if p is in the convex counterclockwise polygon, or within
+// a small distance of it.
+func inPolygon(poly []mgl64.Vec2, p mgl64.Vec2) bool {
+	if len(poly) == 0 {
+		return false
+	}
+	for k, a := range poly {
+		b := poly[(k+1)%len(poly)]
+		if l := b.Sub(a).Len(); l > 0 && cross(a, b, p)/l < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(s []int, x int) bool {
+	for _, v := range s {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package kdtree
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// balance is the scapegoat tree's alpha: a subtree is rebuilt when one of its
+// children holds more than this fraction of its nodes, and the whole tree is
+// rebuilt when deletes shrink it below this fraction of its largest size.
+const balance = 0.7
+
+// Insert adds p to the tree and returns its new node, whose Index is -1. The
+// point is not copied, and must have the same number of dimensions as the
+// tree's points.
+//
+// Unbalanced subtrees are rebuilt as needed, which moves nodes around the
+// tree but keeps the nodes themselves, so nodes returned by Insert and the
+// searches remain valid.
+func (t *Tree) Insert(p Point) *Node {
+	n := &Node{Point: p, Index: -1, size: 1}
+	if t.Root == nil {
+		n.Range = make([]mgl64.Vec2, len(p))
+		for axis := range p {
+			n.Range[axis] = mgl64.Vec2{p[axis], p[axis]}
+		}
+		t.Root = n
+		t.maxSize = 1
+		return n
+	}
+	dims := len(t.Root.Point)
+	if len(p) != dims {
+		panic("kdtree: points have different dimensions")
+	}
+	t.growRange(p)
+
+	// walk down to the leaf position, counting the new node in the sizes of
+	// its ancestors.
+	parent := t.Root
+	for {
+		parent.size++
+		child := &parent.Left
+		if p[parent.Axis] > parent.Point[parent.Axis] {
+			child = &parent.Right
+		}
+		if *child == nil {
+			*child = n
+			break
+		}
+		parent = *child
+	}
+	l, r := splitRange(parent.Range, parent.Axis, parent.Point[parent.Axis])
+	n.Range = l
+	if parent.Right == n {
+		n.Range = r
+	}
+	n.Axis = (parent.Axis + 1) % dims
+	n.Parent = parent
+
+	if t.Len() > t.maxSize {
+		t.maxSize = t.Len()
+	}
+
+	// rebuild the highest unbalanced ancestor.
+	var scapegoat *Node
+	for a := parent; a != nil; a = a.Parent {
+		if float64(maxInt(size(a.Left), size(a.Right))) > balance*float64(a.size) {
+			scapegoat = a
+		}
+	}
+	if scapegoat != nil {
+		t.rebuild(scapegoat)
+	}
+	return n
+}
+
+// Delete removes a node at p from the tree and returns it, or returns nil if
+// there is no node at p.
+func (t *Tree) Delete(p Point) *Node {
+	var found *Node
+	boxSearch(t.Root, p, p, func(n *Node) bool {
+		found = n
+		return false
+	})
+	if found != nil {
+		t.DeleteNode(found)
+	}
+	return found
+}
+
+// DeleteNode removes n, which must be in the tree, from the tree. Its place
+// is taken by the node nearest to it on its splitting axis from one of its
+// subtrees.
+func (t *Tree) DeleteNode(n *Node) {
+	if t.maxSize < t.Len() {
+		t.maxSize = t.Len()
+	}
+	for a := n.Parent; a != nil; a = a.Parent {
+		a.size--
+	}
+	t.remove(n)
+	n.Left, n.Right, n.Parent, n.size = nil, nil, nil, 1
+
+	if float64(t.Len()) < balance*float64(t.maxSize) {
+		if t.Root != nil {
+			t.rebuild(t.Root)
+		}
+		t.maxSize = t.Len()
+	}
+}
+
+// remove takes n out of the tree. The sizes of n's ancestors must already be
+// updated.
+func (t *Tree) remove(n *Node) {
+	var repl *Node
+	switch {
+	case n.Right != nil:
+		// everything in the right subtree is >= its minimum, and everything in
+		// the left is <= n's split, which is <= that minimum.
+		repl = findMin(n.Right, n.Axis)
+	case n.Left != nil:
+		repl = findMax(n.Left, n.Axis)
+	default:
+		t.replaceChild(n, nil)
+		return
+	}
+
+	// take repl out of its place, then put it in n's.
+	for a := repl.Parent; a != n; a = a.Parent {
+		a.size--
+	}
+	t.remove(repl)
+	repl.Axis, repl.Range, repl.size = n.Axis, n.Range, n.size-1
+	n.Range = append([]mgl64.Vec2(nil), n.Range...)
+	repl.Left, repl.Right, repl.Parent = n.Left, n.Right, n.Parent
+	if repl.Left != nil {
+		repl.Left.Parent = repl
+	}
+	if repl.Right != nil {
+		repl.Right.Parent = repl
+	}
+	t.replaceChild(n, repl)
+
+	// the split moved, so the ranges bounded by it move too.
+	old, split := n.Point[n.Axis], repl.Point[n.Axis]
+	moveBound(repl.Left, n.Axis, 1, old, split)
+	moveBound(repl.Right, n.Axis, 0, old, split)
+}
+
+// replaceChild puts repl in the place of n in n's parent (or the root).
+func (t *Tree) replaceChild(n, repl *Node) {
+	switch p := n.Parent; {
+	case p == nil:
+		t.Root = repl
+	case p.Left == n:
+		p.Left = repl
+	default:
+		p.Right = repl
+	}
+	if repl != nil {
+		repl.Parent = n.Parent
+	}
+}
+
+// findMin finds the node with the smallest value on axis in the subtree.
+func findMin(root *Node, axis int) *Node {
+	if root == nil {
+		return nil
+	}
+	best := root
+	candidates := []*Node{root.Left}
+	if root.Axis != axis {
+		candidates = append(candidates, root.Right)
+	}
+	for _, c := range candidates {
+		if m := findMin(c, axis); m != nil && m.Point[axis] < best.Point[axis] {
+			best = m
+		}
+	}
+	return best
+}
+
+// findMax finds the node with the largest value on axis in the subtree.
+func findMax(root *Node, axis int) *Node {
+	if root == nil {
+		return nil
+	}
+	best := root
+	candidates := []*Node{root.Right}
+	if root.Axis != axis {
+		candidates = append(candidates, root.Left)
+	}
+	for _, c := range candidates {
+		if m := findMax(c, axis); m != nil && m.Point[axis] > best.Point[axis] {
+			best = m
+		}
+	}
+	return best
+}
+
+// moveBound changes the side (0=min, 1=max) of the ranges on axis which are
+// at old to v, in the subtree.
+func moveBound(root *Node, axis, side int, old, v float64) {
+	if root == nil || root.Range[axis][side] != old {
+		return
+	}
+	root.Range[axis][side] = v
+	moveBound(root.Left, axis, side, old, v)
+	moveBound(root.Right, axis, side, old, v)
+}
+
+// growRange grows the range of the tree to hold p.
+func (t *Tree) growRange(p Point) {
+	for axis, v := range p {
+		if old := t.Root.Range[axis][0]; v < old {
+			moveBound(t.Root, axis, 0, old, v)
+		}
+		if old := t.Root.Range[axis][1]; v > old {
+			moveBound(t.Root, axis, 1, old, v)
+		}
+	}
+}
+
+// rebuild rebalances the subtree rooted at n, reusing its nodes.
+func (t *Tree) rebuild(n *Node) {
+	nodes := make([]*Node, 0, n.size)
+	PreOrderTraversal(n, func(node *Node) {
+		nodes = append(nodes, node)
+	})
+	parent := n.Parent
+	b := newBuilder(len(nodes), len(n.Point), func(i int) Point { return nodes[i].Point }, 1)
+	b.nodes = nodes
+	root := b.build(0, len(nodes), n.Axis, parent, n.Range, 1)
+	switch {
+	case parent == nil:
+		t.Root = root
+	case parent.Left == n:
+		parent.Left = root
+	default:
+		parent.Right = root
+	}
+}
+
+// size gets the number of nodes in the subtree, 0 for nil.
+func size(n *Node) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,181 @@
+package kdtree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkTree checks the structure of the tree: the sizes, parents, ranges and
+// the split of every node.
+func checkTree(t *testing.T, tree *Tree) {
+	t.Helper()
+	if tree.Root != nil && tree.Root.Parent != nil {
+		t.Fatalf("root has a parent")
+	}
+	var check func(n *Node) int
+	check = func(n *Node) int {
+		if n == nil {
+			return 0
+		}
+		for axis, r := range n.Range {
+			if n.Point[axis] < r[0] || n.Point[axis] > r[1] {
+				t.Fatalf("node %v is outside its range %v", n.Point, n.Range)
+			}
+		}
+		split := n.Point[n.Axis]
+		for _, c := range []*Node{n.Left, n.Right} {
+			if c == nil {
+				continue
+			}
+			if c.Parent != n {
+				t.Fatalf("child %v of %v has parent %v", c.Point, n.Point, c.Parent)
+			}
+			if c.Axis != (n.Axis+1)%len(n.Point) {
+				t.Fatalf("child %v of %v has axis %d", c.Point, n.Point, c.Axis)
+			}
+		}
+		PreOrderTraversal(n.Left, func(c *Node) {
+			if c.Point[n.Axis] > split {
+				t.Fatalf("%v is left of %v", c.Point, n.Point)
+			}
+		})
+		PreOrderTraversal(n.Right, func(c *Node) {
+			if c.Point[n.Axis] < split {
+				t.Fatalf("%v is right of %v", c.Point, n.Point)
+			}
+		})
+		if s := 1 + check(n.Left) + check(n.Right); s != n.size {
+			t.Fatalf("node %v has size %d, want %d", n.Point, n.size, s)
+		}
+		return n.size
+	}
+	check(tree.Root)
+}
+
+func height(n *Node) int {
+	if n == nil {
+		return 0
+	}
+	return 1 + maxInt(height(n.Left), height(n.Right))
+}
+
+func TestTree_InsertDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{1, 2, 3, 5} {
+		t.Run(fmt.Sprintf("%dd", dims), func(t *testing.T) {
+			tree := NewTree(randomPoints(rng, 100, dims), Euclidean)
+			live := map[*Node]bool{}
+			PreOrderTraversal(tree.Root, func(n *Node) { live[n] = true })
+
+			for i := 0; i < 3000; i++ {
+				if rng.Intn(3) > 0 || len(live) == 0 {
+					// points outside the starting range grow it.
+					n := tree.Insert(randomPoint(rng, dims, -20, 120))
+					live[n] = true
+				} else {
+					for n := range live {
+						tree.DeleteNode(n)
+						delete(live, n)
+						break
+					}
+				}
+
+				if i%100 == 0 {
+					checkTree(t, tree)
+				}
+				if tree.Len() != len(live) {
+					t.Fatalf("Len() = %d, want %d", tree.Len(), len(live))
+				}
+			}
+			checkTree(t, tree)
+			if h, max := height(tree.Root), 2*math.Log(float64(tree.Len()))/math.Log(1/balance); float64(h) > max {
+				t.Errorf("tree of %d nodes has height %d, want at most %.0f", tree.Len(), h, max)
+			}
+
+			pts := []Point{}
+			for n := range live {
+				pts = append(pts, n.Point)
+			}
+			for i := 0; i < 100; i++ {
+				q := randomPoint(rng, dims, -20, 120)
+				want := bruteForce(pts, q, Euclidean)
+				for j, n := range tree.NearestKNeighbors(5, q) {
+					if !live[n.Node] {
+						t.Fatalf("NearestKNeighbors(%v) found deleted node %v", q, n.Point)
+					}
+					if d := Euclidean.Distance(n.Point, q); d != want[j] || d != n.Dist {
+						t.Fatalf("NearestKNeighbors(%v)[%d] at %v, want %v", q, j, d, want[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTree_Delete(t *testing.T) {
+	tree := &Tree{Metric: Euclidean}
+	pts := []Point{{5, 5}, {2, 8}, {8, 2}, {5, 1}, {5, 9}, {1, 1}, {9, 9}}
+	for _, p := range pts {
+		if n := tree.Insert(p); n.Index != -1 {
+			t.Errorf("Insert() made node with Index %d", n.Index)
+		}
+	}
+	checkTree(t, tree)
+
+	if n := tree.Delete(Point{3, 3}); n != nil {
+		t.Errorf("Delete() of missing point = %v", n.Point)
+	}
+	for i, p := range pts {
+		n := tree.Delete(p)
+		if n == nil || n.Point[0] != p[0] || n.Point[1] != p[1] {
+			t.Fatalf("Delete(%v) = %v", p, n)
+		}
+		checkTree(t, tree)
+		if tree.Len() != len(pts)-i-1 {
+			t.Fatalf("Len() = %d after %d deletes", tree.Len(), i+1)
+		}
+	}
+	if tree.Root != nil {
+		t.Errorf("Root = %v after deleting every point", tree.Root.Point)
+	}
+}
+
+func TestTree_NilMetric(t *testing.T) {
+	// a zero Tree searches with the Euclidean metric
+	tree := &Tree{}
+	for _, p := range []Point{{0, 0}, {3, 0}, {0, 4}, {2, 2}} {
+		tree.Insert(p)
+	}
+	want := Euclidean.Distance(Point{2, 2}, Point{1, 3})
+	if n := tree.NearestNeighbor(Point{1, 3}); n == nil || n.Point[0] != 2 || n.Point[1] != 2 {
+		t.Errorf("NearestNeighbor() = %v, want [2 2]", n)
+	}
+	if got := tree.NearestKNeighbors(1, Point{1, 3}); len(got) != 1 || got[0].Dist != want {
+		t.Errorf("NearestKNeighbors() = %v, want a distance of %v", got, want)
+	}
+	if got, _ := tree.ApproxNearestKNeighbors(1, Point{1, 3}, ApproxOptions{}); len(got) != 1 || got[0].Dist != want {
+		t.Errorf("ApproxNearestKNeighbors() = %v, want a distance of %v", got, want)
+	}
+	if got := tree.Radius(Point{1, 3}, want, 0); len(got) != 2 {
+		t.Errorf("Radius() found %d nodes, want 2", len(got))
+	}
+	if got := tree.Box(Point{0, 2}, Point{2, 4}, 0); len(got) != 2 || got[0].Dist != want {
+		t.Errorf("Box() = %v, want 2 nodes, the first at %v", got, want)
+	}
+}
+
+func BenchmarkTree_InsertDelete(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewTree(randomPoints(rng, 100000, 3), Euclidean)
+	nodes := []*Node{}
+	PreOrderTraversal(tree.Root, func(n *Node) { nodes = append(nodes, n) })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// move a random point
+		j := rng.Intn(len(nodes))
+		tree.DeleteNode(nodes[j])
+		nodes[j] = tree.Insert(randomPoint(rng, 3, 0, 100))
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"fun/kdtree"
 	stdrand "math/rand"
+	"os"
 	"time"
 
 	"github.com/cpmech/gosl/plt"
@@ -39,26 +41,25 @@ func main() {
 	}
 	// points = []mgl64.Vec2{{2, 3}, {5, 4}, {9, 6}, {4, 7}, {8, 1}, {7, 8}} // test data
 
-	Dist = Euclidean
-
 	start := time.Now()
-	root := BuildTree(points) // make tree
+	tree := kdtree.NewTree(kdtree.Vec2Points(points), kdtree.Euclidean) // make tree
 	fmt.Println("tree build time (ms):", time.Since(start).Seconds()*1000)
 
-	searchpt := mgl64.Vec2{*x, *y}
-	result := []*Node{}
+	searchpt := kdtree.Point{*x, *y}
+	result := []*kdtree.Node{}
 	start = time.Now()
 	if *k == 1 {
-		found := NearestNeighbor(root, searchpt)
+		found := tree.NearestNeighbor(searchpt)
 		result = append(result, found)
 		fmt.Println("nearest neighbor to", searchpt, "is", found.Point)
 		fmt.Println("search took (ms):", time.Since(start).Seconds()*1000)
 	} else if *k > 1 {
 		fmt.Println("the", *k, "nearest neighbors to", searchpt, "are:")
-		result = NearestKNeighbors(root, *k, searchpt)
+		neighbors := tree.NearestKNeighbors(*k, searchpt)
 		fmt.Println("search took (ms):", time.Since(start).Seconds()*1000)
-		for _, n := range result {
-			fmt.Println(n.Point)
+		for _, n := range neighbors {
+			fmt.Println(n.Point, "at", n.Dist)
+			result = append(result, n.Node)
 		}
 
 	} else {
@@ -72,29 +73,29 @@ func main() {
 		vStyle := &plt.A{C: "#FF0000"}
 		hStyle := &plt.A{C: "#0000FF"}
 
-		action := func(node *Node) {
+		action := func(node *kdtree.Node) {
 			// print vertical line for X-median node,
 			// and horizontal line for Y-median node.
 			if node.Axis == 0 {
 				plt.Polyline([][]float64{
-					{node.Point.X(), node.Range[1][0]},
-					{node.Point.X(), node.Range[1][1]}},
+					{node.Point[0], node.Range[1][0]},
+					{node.Point[0], node.Range[1][1]}},
 					vStyle)
 			} else {
 				plt.Polyline([][]float64{
-					{node.Range[0][0], node.Point.Y()},
-					{node.Range[0][1], node.Point.Y()}},
+					{node.Range[0][0], node.Point[1]},
+					{node.Range[0][1], node.Point[1]}},
 					hStyle)
 			}
 			// plot the point
-			plt.PlotOne(node.Point.X(), node.Point.Y(), ptStyle)
+			plt.PlotOne(node.Point[0], node.Point[1], ptStyle)
 		}
 
-		PreOrderTraversal(root, action) // correct way to print tree
+		kdtree.PreOrderTraversal(tree.Root, action) // correct way to print tree
 
-		plt.PlotOne(searchpt.X(), searchpt.Y(), &plt.A{C: "#00AA00", M: "x"}) // plot search pt
-		for _, r := range result {                                            // plot found point(s)
-			plt.PlotOne(r.Point.X(), r.Point.Y(), foundStyle)
+		plt.PlotOne(searchpt[0], searchpt[1], &plt.A{C: "#00AA00", M: "x"}) // plot search pt
+		for _, r := range result {                                          // plot found point(s)
+			plt.PlotOne(r.Point[0], r.Point[1], foundStyle)
 		}
 
 		plt.SetAxis(min, max, min, max)
@@ -104,9 +105,20 @@ func main() {
 	// attempt voronoi plot
 	if runVoronoi {
 		start = time.Now()
-		Voronoi(points, max, max, Euclidean, "vor-eucl.png")
-		Voronoi(points, max, max, Manhattan, "vor-manh.png")
-		Voronoi(points, max, max, Chebyshev, "vor-cheb.png")
+		plots := []struct {
+			m        kdtree.Metric
+			filename string
+		}{
+			{kdtree.Euclidean, "vor-eucl.png"},
+			{kdtree.Manhattan, "vor-manh.png"},
+			{kdtree.Chebyshev, "vor-cheb.png"},
+		}
+		for _, p := range plots {
+			if err := kdtree.Voronoi(points, max, max, p.m, p.filename); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
 		fmt.Println("avg time for voronoi plot (s):", time.Since(start).Seconds()/3)
 	}
 }
@@ -0,0 +1,225 @@
+package kdtree
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// FlatTree is a static kd tree stored in 2 flat arrays instead of linked
+// nodes, which takes much less memory and searches faster on large trees.
+//
+// The tree is implicit in the order of the points: the subtree of the
+// positions [lo,hi) has its root at the middle position lo+(hi-lo)/2, and
+// its children at [lo,mid) and [mid+1,hi). The root of the tree splits on
+// axis 0, its children on axis 1 and so on. This is the same tree BuildTree
+// makes.
+type FlatTree struct {
+	Metric Metric
+
+	dims   int
+	coords []float64 // the point at each position, dims coordinates each
+	index  []int32   // the index in the items of the point at each position
+
+	unmap func() error // for trees from OpenFlatTree, releases the mapping
+}
+
+// FlatNeighbor is a point found by a search of a FlatTree, with its distance
+// from the search point.
+type FlatNeighbor struct {
+	Index int   // index of the point in the items the tree was made from
+	Point Point // shares the tree's memory, so must not be changed
+	Dist  float64
+}
+
+// FlatNode is a node of a FlatTree, for traversals.
+type FlatNode struct {
+	Index int   // index of the point in the items the tree was made from
+	Point Point // shares the tree's memory, so must not be changed
+	Axis  int
+	Range []mgl64.Vec2 // {min, max} on each axis
+}
+
+// NewFlatTree makes a FlatTree of the items, which is searched with the
+// metric m (Euclidean if nil). The points are copied into the tree. All items
+// must have the same number of dimensions. The work is split among up to
+// workers goroutines for large trees.
+func NewFlatTree(items []Point, m Metric, workers int) *FlatTree {
+	if m == nil {
+		m = Euclidean
+	}
+	t := &FlatTree{Metric: m}
+	if len(items) == 0 {
+		return t
+	}
+	t.dims = len(items[0])
+	if t.dims == 0 {
+		panic("kdtree: points have no dimensions")
+	}
+	for _, p := range items {
+		if len(p) != t.dims {
+			panic("kdtree: points have different dimensions")
+		}
+	}
+
+	t.index = make([]int32, len(items))
+	b := newBuilder(len(items), t.dims, func(i int) Point { return items[i] }, workers)
+	b.order(0, len(items), 0, t.index, workers)
+
+	t.coords = make([]float64, len(items)*t.dims)
+	for pos, i := range t.index {
+		copy(t.coords[pos*t.dims:], items[i])
+	}
+	return t
+}
+
+// Len gets the number of points in the tree.
+func (t *FlatTree) Len() int {
+	return len(t.index)
+}
+
+// Dims gets the number of dimensions of the points in the tree.
+func (t *FlatTree) Dims() int {
+	return t.dims
+}
+
+// point gets the point at position pos.
+func (t *FlatTree) point(pos int) Point {
+	i := pos * t.dims
+	return Point(t.coords[i : i+t.dims : i+t.dims])
+}
+
+// NearestNeighbor finds the nearest neighbor to searchPt. It returns false if
+// the tree is empty.
+func (t *FlatTree) NearestNeighbor(searchPt Point) (FlatNeighbor, bool) {
+	best := t.nearestFrom(searchPt, -1)
+	if best.pos < 0 {
+		return FlatNeighbor{}, false
+	}
+	return t.neighbor(best), true
+}
+
+// nearestFrom finds the nearest neighbor to searchPt, starting with the point
+// at position hint (if >= 0) as the best candidate. That is much faster if it
+// is near, such as the nearest neighbor of a nearby point.
+func (t *FlatTree) nearestFrom(searchPt Point, hint int) flatNeigh {
+	best := flatNeigh{-1, math.Inf(0)}
+	if hint >= 0 {
+		best = flatNeigh{hint, t.Metric.Distance(t.point(hint), searchPt)}
+	}
+	t.nnSearch(0, t.Len(), 0, searchPt, &best)
+	return best
+}
+
+// used in flat tree searches for best candidate(s)
+type flatNeigh struct {
+	pos  int
+	dist float64
+}
+
+func (n flatNeigh) rank() float64 { return n.dist }
+
+func (t *FlatTree) neighbor(n flatNeigh) FlatNeighbor {
+	return FlatNeighbor{Index: int(t.index[n.pos]), Point: t.point(n.pos), Dist: n.dist}
+}
+
+// does actual search of the subtree at [lo,hi) splitting on axis, like
+// nnSearch.
+func (t *FlatTree) nnSearch(lo, hi, axis int, searchPt Point, curBest *flatNeigh) {
+	if lo == hi {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	p := t.point(mid)
+	next := (axis + 1) % t.dims
+
+	// search the side of the splitting plane holding searchPt first.
+	nearLo, nearHi, farLo, farHi := lo, mid, mid+1, hi
+	if searchPt[axis] > p[axis] {
+		nearLo, nearHi, farLo, farHi = farLo, farHi, nearLo, nearHi
+	}
+	t.nnSearch(nearLo, nearHi, next, searchPt, curBest)
+
+	if dist := t.Metric.Distance(p, searchPt); curBest.pos < 0 || dist < curBest.dist {
+		curBest.pos = mid
+		curBest.dist = dist
+	}
+
+	if t.Metric.AxisDistance(axis, math.Abs(p[axis]-searchPt[axis])) < curBest.dist {
+		t.nnSearch(farLo, farHi, next, searchPt, curBest)
+	}
+}
+
+// NearestKNeighbors returns the nearest [0,k] neighbors to the search point
+// with their distances, nearest first. If fewer than k are found, the
+// returned slice will be as long as the number found.
+func (t *FlatTree) NearestKNeighbors(k int, searchPt Point) []FlatNeighbor {
+	if k <= 0 {
+		return nil
+	}
+	bests := newBestK[flatNeigh](k, t.Len())
+	t.knnSearch(0, t.Len(), 0, searchPt, bests)
+	sorted := bests.sorted()
+	found := make([]FlatNeighbor, len(sorted))
+	for i, b := range sorted {
+		found[i] = t.neighbor(b)
+	}
+	return found
+}
+
+// does nn search for k points. curBests holds the best points found so far.
+func (t *FlatTree) knnSearch(lo, hi, axis int, searchPt Point, curBests *bestK[flatNeigh]) {
+	if lo == hi {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	p := t.point(mid)
+	next := (axis + 1) % t.dims
+
+	nearLo, nearHi, farLo, farHi := lo, mid, mid+1, hi
+	if searchPt[axis] > p[axis] {
+		nearLo, nearHi, farLo, farHi = farLo, farHi, nearLo, nearHi
+	}
+	t.knnSearch(nearLo, nearHi, next, searchPt, curBests)
+
+	curBests.push(flatNeigh{mid, t.Metric.Distance(p, searchPt)})
+
+	if t.Metric.AxisDistance(axis, math.Abs(p[axis]-searchPt[axis])) < curBests.worst() {
+		t.knnSearch(farLo, farHi, next, searchPt, curBests)
+	}
+}
+
+// PreOrderTraversal traverses the tree in a depth-first manner, performing
+// "action" on each node before visiting its children. The nodes' ranges are
+// computed during the traversal, and the Range of the root is the bounding
+// box of the points.
+func (t *FlatTree) PreOrderTraversal(action func(node FlatNode)) {
+	if t.Len() == 0 {
+		return
+	}
+	rng := make([]mgl64.Vec2, t.dims)
+	for axis := range rng {
+		rng[axis] = mgl64.Vec2{math.Inf(1), math.Inf(-1)}
+	}
+	for pos := 0; pos < t.Len(); pos++ {
+		for axis, v := range t.point(pos) {
+			rng[axis][0] = math.Min(rng[axis][0], v)
+			rng[axis][1] = math.Max(rng[axis][1], v)
+		}
+	}
+	t.preOrder(0, t.Len(), 0, rng, action)
+}
+
+func (t *FlatTree) preOrder(lo, hi, axis int, rng []mgl64.Vec2, action func(node FlatNode)) {
+	if lo == hi {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	p := t.point(mid)
+	action(FlatNode{Index: int(t.index[mid]), Point: p, Axis: axis, Range: rng})
+
+	l, r := splitRange(rng, axis, p[axis])
+	next := (axis + 1) % t.dims
+	t.preOrder(lo, mid, next, l, action)
+	t.preOrder(mid+1, hi, next, r, action)
+}
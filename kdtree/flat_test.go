@@ -0,0 +1,98 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFlatTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{1, 2, 3, 8, 16} {
+		for name, m := range testMetrics(dims) {
+			t.Run(fmt.Sprintf("%s %dd", name, dims), func(t *testing.T) {
+				pts := randomPoints(rng, 1000, dims)
+				flat := NewFlatTree(pts, m, 2)
+				tree := NewTree(pts, m)
+				if flat.Len() != len(pts) || flat.Dims() != dims {
+					t.Fatalf("Len(), Dims() = %d, %d", flat.Len(), flat.Dims())
+				}
+
+				for i := 0; i < 100; i++ {
+					q := randomPoint(rng, dims, -10, 110)
+
+					got, ok := flat.NearestNeighbor(q)
+					want := tree.NearestNeighbor(q)
+					if !ok || !reflect.DeepEqual(got.Point, want.Point) || !reflect.DeepEqual(pts[got.Index], got.Point) {
+						t.Fatalf("NearestNeighbor(%v) = %v, want %v", q, got, want.Point)
+					}
+					if got.Dist != m.Distance(got.Point, q) {
+						t.Fatalf("NearestNeighbor(%v) at %v, want %v", q, got.Dist, m.Distance(got.Point, q))
+					}
+
+					found := flat.NearestKNeighbors(10, q)
+					wantNodes := tree.NearestKNeighbors(10, q)
+					if len(found) != len(wantNodes) {
+						t.Fatalf("NearestKNeighbors(%v) found %d points, want %d", q, len(found), len(wantNodes))
+					}
+					for j, n := range found {
+						if !reflect.DeepEqual(n.Point, wantNodes[j].Point) || !reflect.DeepEqual(pts[n.Index], n.Point) {
+							t.Fatalf("NearestKNeighbors(%v)[%d] = %v, want %v", q, j, n, wantNodes[j].Point)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFlatTree_PreOrderTraversal(t *testing.T) {
+	pts := randomPoints(rand.New(rand.NewSource(1)), 500, 3)
+	var want, got []FlatNode
+	PreOrderTraversal(BuildTree(pts), func(n *Node) {
+		want = append(want, FlatNode{Point: n.Point, Axis: n.Axis, Range: n.Range})
+	})
+	NewFlatTree(pts, nil, 1).PreOrderTraversal(func(n FlatNode) {
+		n.Index = 0
+		got = append(got, n)
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatTree and Tree traversals differ")
+	}
+}
+
+func TestFlatTree_Empty(t *testing.T) {
+	flat := NewFlatTree(nil, nil, 1)
+	if n, ok := flat.NearestNeighbor(Point{1, 2}); ok {
+		t.Errorf("NearestNeighbor() in empty tree = %v", n)
+	}
+	if n := flat.NearestKNeighbors(3, Point{1, 2}); len(n) != 0 {
+		t.Errorf("NearestKNeighbors() in empty tree = %v", n)
+	}
+	flat.PreOrderTraversal(func(n FlatNode) {
+		t.Errorf("PreOrderTraversal() of empty tree visited %v", n)
+	})
+}
+
+const benchQueries = 10000
+
+func BenchmarkTree_NearestKNeighbors(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewTree(randomPoints(rng, 1000000, 3), Euclidean)
+	qs := randomPoints(rng, benchQueries, 3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.NearestKNeighbors(10, qs[i%benchQueries])
+	}
+}
+
+func BenchmarkFlatTree_NearestKNeighbors(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewFlatTree(randomPoints(rng, 1000000, 3), Euclidean, 1)
+	qs := randomPoints(rng, benchQueries, 3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.NearestKNeighbors(10, qs[i%benchQueries])
+	}
+}
@@ -0,0 +1,91 @@
+package kdtree
+
+import "math"
+
+// ranked is a search candidate, ranked by its distance.
+type ranked interface {
+	rank() float64
+}
+
+func (n Neighbor) rank() float64 { return n.Dist }
+
+// bestK keeps the k best (nearest) candidates offered to it in a max-heap,
+// so the worst of them is always at hand, and is replaced in O(log k).
+type bestK[T ranked] struct {
+	k     int
+	items []T
+}
+
+// newBestK makes a bestK for k candidates, of at most n offered.
+func newBestK[T ranked](k, n int) *bestK[T] {
+	if n > k {
+		n = k
+	}
+	return &bestK[T]{k: k, items: make([]T, 0, n)}
+}
+
+// worst gets the rank of the worst candidate kept, or +Inf until there are k
+// candidates, since any candidate is kept until then.
+func (b *bestK[T]) worst() float64 {
+	if len(b.items) < b.k {
+		return math.Inf(1)
+	}
+	return b.items[0].rank()
+}
+
+// push offers x, which is kept if there are less than k candidates, or if it
+// is better than the worst, which is dropped.
+func (b *bestK[T]) push(x T) {
+	if len(b.items) < b.k {
+		b.items = append(b.items, x)
+		b.up(len(b.items) - 1)
+		return
+	}
+	if x.rank() >= b.items[0].rank() {
+		return
+	}
+	b.items[0] = x
+	b.down(0, len(b.items))
+}
+
+// sorted gets the candidates, best first. It leaves b empty.
+func (b *bestK[T]) sorted() []T {
+	s := b.items
+	for n := len(s) - 1; n > 0; n-- {
+		s[0], s[n] = s[n], s[0]
+		b.down(0, n)
+	}
+	b.items = nil
+	return s
+}
+
+func (b *bestK[T]) up(i int) {
+	s := b.items
+	for i > 0 {
+		parent := (i - 1) / 2
+		if s[parent].rank() >= s[i].rank() {
+			break
+		}
+		s[parent], s[i] = s[i], s[parent]
+		i = parent
+	}
+}
+
+// down moves the item at i down the heap of the first n items.
+func (b *bestK[T]) down(i, n int) {
+	s := b.items
+	for {
+		largest := i
+		if l := 2*i + 1; l < n && s[l].rank() > s[largest].rank() {
+			largest = l
+		}
+		if r := 2*i + 2; r < n && s[r].rank() > s[largest].rank() {
+			largest = r
+		}
+		if largest == i {
+			return
+		}
+		s[i], s[largest] = s[largest], s[i]
+		i = largest
+	}
+}
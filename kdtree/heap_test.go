@@ -0,0 +1,46 @@
+package kdtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestBestK(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, k := range []int{1, 2, 7, 100, 5000} {
+		dists := make([]float64, 3000)
+		b := newBestK[Neighbor](k, len(dists))
+		for i := range dists {
+			dists[i] = float64(rng.Intn(1000))
+			b.push(Neighbor{Dist: dists[i]})
+		}
+		sort.Float64s(dists)
+		if k < len(dists) {
+			dists = dists[:k]
+			if b.worst() != dists[k-1] {
+				t.Errorf("k=%d: worst() = %v, want %v", k, b.worst(), dists[k-1])
+			}
+		}
+
+		got := b.sorted()
+		if len(got) != len(dists) {
+			t.Fatalf("k=%d: kept %d, want %d", k, len(got), len(dists))
+		}
+		for i, n := range got {
+			if n.Dist != dists[i] {
+				t.Fatalf("k=%d: sorted()[%d] = %v, want %v", k, i, n.Dist, dists[i])
+			}
+		}
+	}
+}
+
+func BenchmarkFlatTree_NearestKNeighbors5000(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewFlatTree(randomPoints(rng, 1000000, 3), Euclidean, 1)
+	qs := randomPoints(rng, benchQueries, 3)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.NearestKNeighbors(5000, qs[i%benchQueries])
+	}
+}
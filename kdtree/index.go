@@ -0,0 +1,98 @@
+package kdtree
+
+// Item is a point with a value attached, such as the record it came from.
+type Item[T any] struct {
+	Point Point
+	Value T
+}
+
+// Index is a static kd tree of items, whose searches give the items' values.
+type Index[T any] struct {
+	tree  *FlatTree
+	items []Item[T]
+}
+
+// Match is an item found by a search of an Index, with its distance from the
+// search point.
+type Match[T any] struct {
+	Item[T]
+	Index int // index of the item in the items given to NewIndex
+	Dist  float64
+}
+
+// NewIndex makes an Index of the items, which is searched with the metric m
+// (Euclidean if nil). The items are not copied, but their points are. All
+// items must have the same number of dimensions. The work is split among up
+// to workers goroutines for large indexes.
+func NewIndex[T any](items []Item[T], m Metric, workers int) *Index[T] {
+	pts := make([]Point, len(items))
+	for i, it := range items {
+		pts[i] = it.Point
+	}
+	return &Index[T]{tree: NewFlatTree(pts, m, workers), items: items}
+}
+
+// Len gets the number of items in the index.
+func (x *Index[T]) Len() int {
+	return len(x.items)
+}
+
+// Item gets the item at index i of the items given to NewIndex.
+func (x *Index[T]) Item(i int) Item[T] {
+	return x.items[i]
+}
+
+// Tree gets the tree the index searches.
+func (x *Index[T]) Tree() *FlatTree {
+	return x.tree
+}
+
+func (x *Index[T]) match(n FlatNeighbor) Match[T] {
+	return Match[T]{Item: x.items[n.Index], Index: n.Index, Dist: n.Dist}
+}
+
+func (x *Index[T]) matches(found []FlatNeighbor) []Match[T] {
+	matches := make([]Match[T], len(found))
+	for i, n := range found {
+		matches[i] = x.match(n)
+	}
+	return matches
+}
+
+// Nearest finds the item nearest to searchPt. It returns false if the index
+// is empty.
+func (x *Index[T]) Nearest(searchPt Point) (Match[T], bool) {
+	n, ok := x.tree.NearestNeighbor(searchPt)
+	if !ok {
+		return Match[T]{}, false
+	}
+	return x.match(n), true
+}
+
+// NearestK finds the [0,k] items nearest to searchPt, nearest first.
+func (x *Index[T]) NearestK(k int, searchPt Point) []Match[T] {
+	return x.matches(x.tree.NearestKNeighbors(k, searchPt))
+}
+
+// ApproxNearestK finds [0,k] items which are the nearest to searchPt or
+// nearly so, nearest first, and the number of points visited to find them.
+// See ApproxOptions.
+func (x *Index[T]) ApproxNearestK(k int, searchPt Point, opts ApproxOptions) ([]Match[T], int) {
+	found, visited := x.tree.ApproxNearestKNeighbors(k, searchPt, opts)
+	return x.matches(found), visited
+}
+
+// Radius finds the items within distance r (inclusive) of searchPt, nearest
+// first. If limit > 0, the search stops once limit items are found, and those
+// are not necessarily the nearest ones.
+func (x *Index[T]) Radius(searchPt Point, r float64, limit int) []Match[T] {
+	return x.matches(x.tree.Radius(searchPt, r, limit))
+}
+
+// Box finds the items inside the axis-aligned box from min to max
+// (inclusive), with their distances from the center of the box, nearest
+// first. If limit > 0, the search stops once limit items are found, and those
+// are not necessarily the nearest ones.
+func (x *Index[T]) Box(min, max Point, limit int) []Match[T] {
+	return x.matches(x.tree.Box(min, max, limit))
+}
@@ -0,0 +1,119 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+type city struct {
+	name       string
+	population int
+}
+
+func TestIndex(t *testing.T) {
+	items := []Item[city]{
+		{Point{0, 0}, city{"a", 10}},
+		{Point{10, 0}, city{"b", 20}},
+		{Point{0, 10}, city{"c", 30}},
+		{Point{10, 10}, city{"d", 40}},
+	}
+	index := NewIndex(items, Euclidean, 1)
+	if index.Len() != 4 {
+		t.Fatalf("Len() = %d", index.Len())
+	}
+
+	m, ok := index.Nearest(Point{9, 2})
+	if !ok || m.Value.name != "b" || m.Index != 1 || m.Dist != Euclidean.Distance(Point{9, 2}, Point{10, 0}) {
+		t.Errorf("Nearest() = %+v", m)
+	}
+
+	matches := index.NearestK(3, Point{1, 9})
+	want := []string{"c", "a", "d"}
+	for i, m := range matches {
+		if m.Value.name != want[i] || index.Item(m.Index).Value != m.Value {
+			t.Errorf("NearestK()[%d] = %+v, want %s", i, m, want[i])
+		}
+	}
+
+	if _, ok := NewIndex[city](nil, nil, 1).Nearest(Point{0, 0}); ok {
+		t.Error("Nearest() in empty index found an item")
+	}
+}
+
+func TestIndex_Random(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := randomPoints(rng, 1000, 4)
+	items := make([]Item[string], len(pts))
+	for i, p := range pts {
+		items[i] = Item[string]{p, fmt.Sprint(i)}
+	}
+	index := NewIndex(items, Chebyshev, 2)
+	for i := 0; i < 100; i++ {
+		q := randomPoint(rng, 4, 0, 100)
+		want := bruteForce(pts, q, Chebyshev)
+		found, _ := index.ApproxNearestK(5, q, ApproxOptions{})
+		for j, m := range found {
+			if m.Value != fmt.Sprint(m.Index) || m.Dist != want[j] || Chebyshev.Distance(m.Point, q) != m.Dist {
+				t.Fatalf("ApproxNearestK(%v)[%d] = %+v, want distance %v", q, j, m, want[j])
+			}
+		}
+	}
+}
+
+func TestIndex_RadiusBox(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// integer coordinates, so that points lie on the boxes' faces and the
+	// circles.
+	pts := make([]Point, 1000)
+	items := make([]Item[string], len(pts))
+	for i := range pts {
+		pts[i] = Point{float64(rng.Intn(20)), float64(rng.Intn(20)), float64(rng.Intn(20))}
+		items[i] = Item[string]{pts[i], fmt.Sprint(i)}
+	}
+	index := NewIndex(items, Manhattan, 2)
+
+	// check tests that the matches are the items the filter keeps, with the
+	// distances from q, nearest first.
+	check := func(name string, found []Match[string], q Point, keep func(p Point) bool) {
+		t.Helper()
+		want := map[int]bool{}
+		for i, p := range pts {
+			if keep(p) {
+				want[i] = true
+			}
+		}
+		if len(found) != len(want) {
+			t.Fatalf("%s found %d items, want %d", name, len(found), len(want))
+		}
+		for j, m := range found {
+			if !want[m.Index] || m.Value != fmt.Sprint(m.Index) || &m.Point[0] != &pts[m.Index][0] {
+				t.Fatalf("%s[%d] = %+v", name, j, m)
+			}
+			if d := Manhattan.Distance(m.Point, q); m.Dist != d || j > 0 && m.Dist < found[j-1].Dist {
+				t.Fatalf("%s[%d] at %v, want %v, nearest first", name, j, m.Dist, d)
+			}
+		}
+	}
+	for i := 0; i < 50; i++ {
+		q := randomPoint(rng, 3, -2, 22)
+		r := float64(rng.Intn(10))
+		check(fmt.Sprintf("Radius(%v, %v)", q, r), index.Radius(q, r, 0), q, func(p Point) bool {
+			return Manhattan.Distance(p, q) <= r
+		})
+
+		min, max, center := make(Point, 3), make(Point, 3), make(Point, 3)
+		for j := range min {
+			min[j] = float64(rng.Intn(20))
+			max[j] = min[j] + float64(rng.Intn(8))
+			center[j] = (min[j] + max[j]) / 2
+		}
+		check(fmt.Sprintf("Box(%v, %v)", min, max), index.Box(min, max, 0), center, func(p Point) bool {
+			return inBox(p, min, max)
+		})
+
+		if found := index.Box(Point{0, 0, 0}, Point{19, 19, 19}, 5); len(found) != 5 {
+			t.Fatalf("Box() with limit 5 found %d items", len(found))
+		}
+	}
+}
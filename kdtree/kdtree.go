@@ -1,21 +1,47 @@
-package main
+// Package kdtree implements a k-d tree of points in any number of dimensions
+// for nearest neighbor searches, and Voronoi plots built on it.
+package kdtree
 
 import (
 	"math"
-	"sort"
 
 	"github.com/go-gl/mathgl/mgl64"
 )
 
+// Point is a point with any number of dimensions.
+type Point []float64
+
+// Vec2Point makes a 2 dimensional Point from v.
+func Vec2Point(v mgl64.Vec2) Point {
+	return Point{v[0], v[1]}
+}
+
+// Vec2Points makes 2 dimensional Points from vs.
+func Vec2Points(vs []mgl64.Vec2) []Point {
+	pts := make([]Point, len(vs))
+	for i, v := range vs {
+		pts[i] = Vec2Point(v)
+	}
+	return pts
+}
+
+// Vec2 gets the first 2 dimensions of the point.
+func (p Point) Vec2() mgl64.Vec2 {
+	return mgl64.Vec2{p[0], p[1]}
+}
+
 // Node is a node in the kdtree
 type Node struct {
 	Axis   int
-	Range  []mgl64.Vec2 // for plotting only
-	Point  mgl64.Vec2
+	Range  []mgl64.Vec2 // {min, max} on each axis, for plotting only
+	Point  Point
 	Left   *Node
 	Right  *Node
-	Parent *Node // not really used
+	Parent *Node
 	Data   interface{}
+	Index  int // index of the point in the items given to BuildTree, or -1
+
+	size int // number of nodes in the subtree rooted here
 }
 
 // IsLeaf says if the node is a leaf (has no children) or not
@@ -23,92 +49,72 @@ func (n *Node) IsLeaf() bool {
 	return n.Left == nil && n.Right == nil
 }
 
-// BuildTree makes the kd tree and returns its root node. The order of "items"
-// will not be preserved.
-func BuildTree(items []mgl64.Vec2) (root *Node) {
-	return buildTree(items, 0, 2, nil, []mgl64.Vec2{{min, max}, {min, max}})
+// Tree is a kd tree with the metric used for searching it. Searches do not
+// change the tree, so they may run concurrently, but not with Insert or
+// Delete.
+type Tree struct {
+	Root   *Node
+	Metric Metric // Euclidean if nil
+
+	maxSize int // largest size since the last full rebuild
 }
 
-// does actual tree build
-func buildTree(items []mgl64.Vec2, depth, dims int, parent *Node, rng []mgl64.Vec2) (node *Node) {
-	if len(items) == 0 {
-		return nil
+// NewTree builds a tree of the items (see BuildTree) which is searched with
+// the metric m. If m is nil, Euclidean is used.
+func NewTree(items []Point, m Metric) *Tree {
+	if m == nil {
+		m = Euclidean
 	}
+	return &Tree{Root: BuildTree(items), Metric: m, maxSize: len(items)}
+}
 
-	// ascending sort items by axis
-	axis := depth % dims // 0=x, 1=y, 2=z (for Vec3)
-	sort.Slice(items, func(i, j int) bool {
-		return items[i][axis] < items[j][axis]
-	})
-
-	// create node
-	median := len(items) / 2
-	n := &Node{
-		Axis:   axis,
-		Range:  rng,
-		Point:  items[median],
-		Parent: parent}
-
-	// create the "ranges" for the left and right children.
-	// the ranges are used for plotting.
-	var l, r []mgl64.Vec2
-	if axis == 0 {
-		// split horizontal range
-		l = []mgl64.Vec2{{rng[0][0], n.Point[axis]}, rng[1]}
-		r = []mgl64.Vec2{{n.Point[axis], rng[0][1]}, rng[1]}
-	} else {
-		// split vertical range
-		l = []mgl64.Vec2{rng[0], {rng[1][0], n.Point[axis]}}
-		r = []mgl64.Vec2{rng[0], {n.Point[axis], rng[1][1]}}
-
+// metric gets the metric to search with.
+func (t *Tree) metric() Metric {
+	if t.Metric == nil {
+		return Euclidean
 	}
-
-	n.Left = buildTree(items[:median], depth+1, dims, n, l)
-	n.Right = buildTree(items[median+1:], depth+1, dims, n, r)
-
-	return n
+	return t.Metric
 }
 
-// used in nearest neighbor searches for best candidate(s)
-type neigh struct {
-	node *Node
-	dist float64
+// Len gets the number of nodes in the tree.
+func (t *Tree) Len() int {
+	return size(t.Root)
 }
 
-// DistMetric is a type the calculates the distance
-type DistMetric func(mgl64.Vec2, mgl64.Vec2) float64
-
-// Dist is the distance function to be used in the nearest neighbors searches.
-var Dist = Euclidean
-
-// Euclidean is a function that can be used for Dist which provides the
-// euclidean/cartesian/geometric distance.
-func Euclidean(a, b mgl64.Vec2) float64 {
-	return distSq(a, b)
+// NearestNeighbor finds the nearest neighbor to searchPt. Returns nil if the
+// tree is empty.
+func (t *Tree) NearestNeighbor(searchPt Point) *Node {
+	best := Neighbor{nil, math.Inf(0)}
+	nnSearch(t.Root, searchPt, t.metric(), &best)
+	return best.Node
 }
 
-// Manhattan is a function that can be used for Dist which provides the
-// manhattan/taxi cab/snake distance.
-func Manhattan(a, b mgl64.Vec2) float64 {
-	return manhattanSq(a, b)
+// NearestKNeighbors returns the nearest [0,k] neighbors to the search point
+// with their distances, nearest first. If fewer than k are found, the
+// returned slice will be as long as the number found.
+func (t *Tree) NearestKNeighbors(k int, searchPt Point) []Neighbor {
+	if k <= 0 {
+		return nil
+	}
+	bests := newBestK[Neighbor](k, t.Len())
+	knnSearch(t.Root, searchPt, t.metric(), bests)
+	return bests.sorted()
 }
 
-// Chebyshev is a function that can be used for Dist which provides the
-// Chebyshev distance.
-func Chebyshev(a, b mgl64.Vec2) float64 {
-	return chebyshevSq(a, b)
+// NearestNeighbor finds the nearest neighbor to searchPt by Euclidean
+// distance. Returns nil if the tree is empty.
+func NearestNeighbor(root *Node, searchPt Point) *Node {
+	return (&Tree{Root: root, Metric: Euclidean}).NearestNeighbor(searchPt)
 }
 
-// NearestNeighbor finds the nearest neighbor to searchPt. Returns
-// nil if none found (shouldn't do that).
-func NearestNeighbor(root *Node, searchPt mgl64.Vec2) *Node {
-	best := neigh{nil, math.Inf(0)}
-	nnSearch(root, searchPt, &best)
-	return best.node
+// NearestKNeighbors returns the nearest [0,k] neighbors to the search point
+// by Euclidean distance. See Tree.NearestKNeighbors.
+func NearestKNeighbors(root *Node, k int, searchPt Point) []Neighbor {
+	return (&Tree{Root: root, Metric: Euclidean}).NearestKNeighbors(k, searchPt)
 }
 
 // does actual search algorithm
-func nnSearch(root *Node, searchPt mgl64.Vec2, curBest *neigh) {
+func nnSearch(root *Node, searchPt Point, m Metric, curBest *Neighbor) {
 
 	// if the current node is nil, then just return the current bests
 	if root == nil {
@@ -124,24 +130,24 @@ func nnSearch(root *Node, searchPt mgl64.Vec2, curBest *neigh) {
 	} else {
 		goDown = root.Right
 	}
-	nnSearch(goDown, searchPt, curBest)
+	nnSearch(goDown, searchPt, m, curBest)
 	// fmt.Println("examining", root.Point, "current best", curBest)
 
 	// check if current node is better than current best
 	// if current best == nil/inf, set current node to best
-	if dist := Dist(root.Point, searchPt); curBest.node == nil || dist < curBest.dist {
-		curBest.node = root
-		curBest.dist = dist
+	if dist := m.Distance(root.Point, searchPt); curBest.Node == nil || dist < curBest.Dist {
+		curBest.Node = root
+		curBest.Dist = dist
 		// fmt.Println(" changing curBest to", root.Point)
 	}
 
 	// check if points could possibly exist on the other side of root's splitting
 	// axis by checking if the distance from the searchPt to axis is less than
 	// the distance to the current best.
-	// search-to-plane = abs(root.Data[axis] - search[axis])
+	// search-to-plane = metric's distance for abs(root.Data[axis] - search[axis])
 	// if search-to-plane <= curbest_dist then go down both branches.
 	// else choose the correct branch.
-	checkBoth := math.Pow(root.Point[root.Axis]-searchPt[root.Axis], 2) < curBest.dist
+	checkBoth := m.AxisDistance(root.Axis, math.Abs(root.Point[root.Axis]-searchPt[root.Axis])) < curBest.Dist
 
 	// go down branch NOT visited earlier based on axial comparison to current node.
 	if goDown == root.Left {
@@ -150,32 +156,15 @@ func nnSearch(root *Node, searchPt mgl64.Vec2, curBest *neigh) {
 		goDown = root.Left
 	}
 	if checkBoth {
-		nnSearch(goDown, searchPt, curBest)
+		nnSearch(goDown, searchPt, m, curBest)
 	}
 
 	return
 }
 
-// NearestKNeighbors returns the nearest [0,k] neighbors to the search point.
-// If fewer than k are found, the returned slice of nodes will be as long as
-// the number found.
-func NearestKNeighbors(root *Node, k int, searchPt mgl64.Vec2) (nodes []*Node) {
-	// MUST have k+1 capacity, or the append() to the bests slice inside
-	// insertAndTrim() will cause a new backing array to be allocated, and so
-	// the array we want to change is NOT changed...very subtle.
-	bests := make([]*neigh, k, k+1)
-	knnSearch(root, searchPt, bests) // will alter bests
-	for _, b := range bests {
-		if b != nil {
-			nodes = append(nodes, b.node)
-		}
-	}
-	return
-}
-
 // does nn search for k nodes
-// curBests is a best-worst ORDERED list of k elements (some of which may be nil)
-func knnSearch(root *Node, searchPt mgl64.Vec2, curBests []*neigh) {
+// curBests holds the best nodes found so far.
+func knnSearch(root *Node, searchPt Point, m Metric, curBests *bestK[Neighbor]) {
 
 	if root == nil {
 		return
@@ -188,33 +177,14 @@ func knnSearch(root *Node, searchPt mgl64.Vec2, curBests []*neigh) {
 	} else {
 		goDown = root.Right
 	}
-	knnSearch(goDown, searchPt, curBests)
+	knnSearch(goDown, searchPt, m, curBests)
 	// fmt.Println("examining", root.Point)
 
-	dist := Dist(root.Point, searchPt)
-	for i := 0; i < len(curBests); i++ {
-		// check each. if found a best.dist > root.dist, insert
-		// to keep order, and remove the worst best from the end.
-		// if nil is encountered, insert.
-		if curBests[i] == nil {
-			// fmt.Println(" adding a default to bests", root.Point, dist)
-			curBests[i] = &neigh{root, dist}
-			break
-		}
-		if dist < curBests[i].dist {
-			// fmt.Println(" adding to bests", root.Point, dist)
-			insertAndTrim(&neigh{root, dist}, i, curBests)
-			break
-		}
-	}
+	curBests.push(Neighbor{root, m.Distance(root.Point, searchPt)})
 
-	// go down branches. use similar process as nnSearch() but use worst best.
-	worstBest := curBests[len(curBests)-1] // would be last
-	checkBoth := false
-	if worstBest == nil ||
-		math.Pow(root.Point[root.Axis]-searchPt[root.Axis], 2) < worstBest.dist {
-		checkBoth = true
-	}
+	// go down branches. use similar process as nnSearch() but use worst best,
+	// which is infinitely far until k nodes are found.
+	checkBoth := m.AxisDistance(root.Axis, math.Abs(root.Point[root.Axis]-searchPt[root.Axis])) < curBests.worst()
 
 	// go down other branch if necessary
 	if goDown == root.Left {
@@ -224,46 +194,18 @@ func knnSearch(root *Node, searchPt mgl64.Vec2, curBests []*neigh) {
 	}
 	if checkBoth {
 		// fmt.Println(" going down other")
-		knnSearch(goDown, searchPt, curBests)
+		knnSearch(goDown, searchPt, m, curBests)
 	}
 
 	return
 }
 
-func insertAndTrim(item *neigh, at int, s []*neigh) {
-	// insert
-	s = append(s, nil)
-	copy(s[at+1:], s[at:])
-	s[at] = item
-
-	// remove end
-	s[len(s)-1] = nil
-	s = s[:len(s)-1]
-}
-
-// finds distance squared between a and b
-func distSq(a, b mgl64.Vec2) float64 {
-	delta := b.Sub(a)
-	return delta[0]*delta[0] + delta[1]*delta[1]
-}
-
-// finds the square of the manhattan distance between a and b
-func manhattanSq(a, b mgl64.Vec2) float64 {
-	s := b.Sub(a)
-	d := math.Abs(s.X()) + math.Abs(s.Y())
-	return d * d
-}
-
-// finds the square of Chebyshev distance between a and b
-func chebyshevSq(a, b mgl64.Vec2) float64 {
-	s := b.Sub(a)
-	d := math.Max(math.Abs(s.X()), math.Abs(s.Y()))
-	return d * d
-}
-
 // PreOrderTraversal traverses the tree in a depth-first manner, performing
 // "action" on the node before visiting children.
 func PreOrderTraversal(root *Node, action func(node *Node)) {
+	if root == nil {
+		return
+	}
 	action(root)
 
 	if root.Left != nil {
@@ -278,6 +220,9 @@ func PreOrderTraversal(root *Node, action func(node *Node)) {
 // InOrderTraversal traverses the tree in a depth-first manner, visiting the
 // left child, then performing "action" on the node, then visiting the right child.
 func InOrderTraversal(root *Node, action func(node *Node)) {
+	if root == nil {
+		return
+	}
 	if root.Left != nil {
 		InOrderTraversal(root.Left, action)
 	}
@@ -293,6 +238,9 @@ func InOrderTraversal(root *Node, action func(node *Node)) {
 // PostOrderTraversal traverses the tree in a depth-first manner, first visitng
 // the children, then performing "action" on the node.
 func PostOrderTraversal(root *Node, action func(node *Node)) {
+	if root == nil {
+		return
+	}
 	if root.Left != nil {
 		PostOrderTraversal(root.Left, action)
 	}
@@ -0,0 +1,173 @@
+package kdtree
+
+import (
+	"fmt"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// randomPoints makes n points of the given dimensions in [0,100).
+func randomPoints(rng *rand.Rand, n, dims int) []Point {
+	pts := make([]Point, n)
+	for i := range pts {
+		pts[i] = randomPoint(rng, dims, 0, 100)
+	}
+	return pts
+}
+
+func randomPoint(rng *rand.Rand, dims int, min, max float64) Point {
+	p := make(Point, dims)
+	for i := range p {
+		p[i] = min + rng.Float64()*(max-min)
+	}
+	return p
+}
+
+// bruteForce gets the distances from q to all of pts, in ascending order.
+func bruteForce(pts []Point, q Point, m Metric) []float64 {
+	dists := make([]float64, len(pts))
+	for i, p := range pts {
+		dists[i] = m.Distance(p, q)
+	}
+	sort.Float64s(dists)
+	return dists
+}
+
+func TestBuildTree(t *testing.T) {
+	if BuildTree(nil) != nil {
+		t.Error("BuildTree(nil) is not nil")
+	}
+
+	pts := []Point{{2, 3}, {5, 4}, {9, 6}, {4, 7}, {8, 1}, {7, 8}}
+	root := BuildTree(pts)
+	if want := []mgl64.Vec2{{2, 9}, {1, 8}}; root.Range[0] != want[0] || root.Range[1] != want[1] {
+		t.Errorf("root Range = %v, want %v", root.Range, want)
+	}
+
+	n := 0
+	PreOrderTraversal(root, func(node *Node) {
+		n++
+		if &pts[node.Index][0] != &node.Point[0] {
+			t.Errorf("node %v has Index %d of %v", node.Point, node.Index, pts[node.Index])
+		}
+		if node.Left != nil && node.Left.Point[node.Axis] > node.Point[node.Axis] {
+			t.Errorf("left child %v of %v is on the wrong side", node.Left.Point, node.Point)
+		}
+		if node.Right != nil && node.Right.Point[node.Axis] < node.Point[node.Axis] {
+			t.Errorf("right child %v of %v is on the wrong side", node.Right.Point, node.Point)
+		}
+	})
+	if n != len(pts) {
+		t.Errorf("tree has %d nodes, want %d", n, len(pts))
+	}
+}
+
+// testMetrics makes the metrics to test trees of the given dimensions with.
+func testMetrics(dims int) map[string]Metric {
+	weights := make([]float64, dims)
+	for i := range weights {
+		weights[i] = float64(i%3) + 0.5
+	}
+	return map[string]Metric{
+		"euclidean":   Euclidean,
+		"manhattan":   Manhattan,
+		"chebyshev":   Chebyshev,
+		"minkowski3":  Minkowski(3),
+		"minkowski.5": Minkowski(0.5),
+		"weighted":    WeightedEuclidean(weights...),
+	}
+}
+
+func TestNearestNeighbor(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for dims := 1; dims <= 16; dims++ {
+		for name, m := range testMetrics(dims) {
+			t.Run(fmt.Sprintf("%s %dd", name, dims), func(t *testing.T) {
+				pts := randomPoints(rng, 500, dims)
+				tree := NewTree(append([]Point(nil), pts...), m)
+				for i := 0; i < 100; i++ {
+					q := randomPoint(rng, dims, -10, 110)
+					want := bruteForce(pts, q, m)
+
+					if got := tree.NearestNeighbor(q); m.Distance(got.Point, q) != want[0] {
+						t.Fatalf("NearestNeighbor(%v) = %v at %v, want distance %v", q, got.Point, m.Distance(got.Point, q), want[0])
+					}
+
+					nodes := tree.NearestKNeighbors(7, q)
+					if len(nodes) != 7 {
+						t.Fatalf("NearestKNeighbors(%v) found %d nodes, want 7", q, len(nodes))
+					}
+					for j, n := range nodes {
+						if got := m.Distance(n.Point, q); got != want[j] || got != n.Dist {
+							t.Fatalf("NearestKNeighbors(%v)[%d] at %v, want %v", q, j, got, want[j])
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNearestNeighbor_Empty(t *testing.T) {
+	if n := NearestNeighbor(nil, Point{0, 0}); n != nil {
+		t.Errorf("NearestNeighbor() in empty tree = %v", n)
+	}
+	if n := NearestKNeighbors(nil, 3, Point{0, 0}); len(n) != 0 {
+		t.Errorf("NearestKNeighbors() in empty tree = %v", n)
+	}
+	root := BuildTree([]Point{{1, 1}, {2, 2}})
+	if n := NearestKNeighbors(root, 5, Point{0, 0}); len(n) != 2 {
+		t.Errorf("NearestKNeighbors() found %d of 2 nodes", len(n))
+	}
+	if n := NearestKNeighbors(root, 0, Point{0, 0}); len(n) != 0 {
+		t.Errorf("NearestKNeighbors() with k=0 = %v", n)
+	}
+}
+
+func TestVoronoi(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "v.png")
+	pts := []mgl64.Vec2{}
+	for _, p := range randomPoints(rand.New(rand.NewSource(1)), 10, 2) {
+		pts = append(pts, p.Vec2())
+	}
+	if err := Voronoi(pts, 40, 30, Manhattan, filename); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 30 {
+		t.Errorf("voronoi plot is %dx%d, want 40x30", b.Dx(), b.Dy())
+	}
+
+	errorTests := []struct {
+		name     string
+		pts      []mgl64.Vec2
+		w, h     int
+		filename string
+	}{
+		{"no points", nil, 10, 10, filename},
+		{"no size", pts, 0, 10, filename},
+		{"bad file", pts, 10, 10, filepath.Join(dir, "missing", "v.png")},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Voronoi(tt.pts, tt.w, tt.h, Euclidean, tt.filename); err == nil {
+				t.Error("Voronoi() did not fail")
+			}
+		})
+	}
+}
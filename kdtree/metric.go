@@ -0,0 +1,128 @@
+package kdtree
+
+import (
+	"math"
+)
+
+// Metric measures the distance between points for the nearest neighbor
+// searches.
+type Metric interface {
+	// Distance gets the distance between a and b.
+	Distance(a, b Point) float64
+
+	// AxisDistance gets a lower bound of the distance between any 2 points
+	// whose coordinates on axis differ by delta (delta >= 0). The searches use
+	// it to skip the far side of a splitting plane, so it must never be more
+	// than Distance.
+	AxisDistance(axis int, delta float64) float64
+}
+
+// The common metrics.
+var (
+	// Euclidean is the euclidean/cartesian/geometric distance.
+	Euclidean Metric = euclidean{}
+	// Manhattan is the manhattan/taxi cab/snake distance.
+	Manhattan Metric = manhattan{}
+	// Chebyshev is the Chebyshev (chessboard) distance.
+	Chebyshev Metric = chebyshev{}
+)
+
+type euclidean struct{}
+
+func (euclidean) Distance(a, b Point) float64 {
+	return math.Sqrt(distSq(a, b))
+}
+
+func (euclidean) AxisDistance(axis int, delta float64) float64 { return delta }
+
+type manhattan struct{}
+
+func (manhattan) Distance(a, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		d += math.Abs(b[i] - a[i])
+	}
+	return d
+}
+
+func (manhattan) AxisDistance(axis int, delta float64) float64 { return delta }
+
+type chebyshev struct{}
+
+func (chebyshev) Distance(a, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		d = math.Max(d, math.Abs(b[i]-a[i]))
+	}
+	return d
+}
+
+func (chebyshev) AxisDistance(axis int, delta float64) float64 { return delta }
+
+// Minkowski makes the metric of the p-norm, (sum |a[i]-b[i]|^p)^(1/p). p=1 is
+// the Manhattan distance, p=2 the Euclidean, and p=+Inf the Chebyshev. It
+// panics if p is not positive.
+func Minkowski(p float64) Metric {
+	switch {
+	case !(p > 0):
+		panic("kdtree: Minkowski p must be positive")
+	case p == 1:
+		return Manhattan
+	case p == 2:
+		return Euclidean
+	case math.IsInf(p, 1):
+		return Chebyshev
+	}
+	return minkowski(p)
+}
+
+type minkowski float64
+
+func (p minkowski) Distance(a, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		d += math.Pow(math.Abs(b[i]-a[i]), float64(p))
+	}
+	return math.Pow(d, 1/float64(p))
+}
+
+func (minkowski) AxisDistance(axis int, delta float64) float64 { return delta }
+
+// WeightedEuclidean makes the euclidean distance with each axis scaled,
+// sqrt(sum weights[i]*(a[i]-b[i])^2). There must be a weight for each
+// dimension of the points. It panics if a weight is negative.
+func WeightedEuclidean(weights ...float64) Metric {
+	w := make(weightedEuclidean, len(weights))
+	for i, wt := range weights {
+		if !(wt >= 0) {
+			panic("kdtree: WeightedEuclidean weights must not be negative")
+		}
+		w[i] = wt
+	}
+	return w
+}
+
+type weightedEuclidean []float64
+
+func (w weightedEuclidean) Distance(a, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		delta := b[i] - a[i]
+		d += w[i] * delta * delta
+	}
+	return math.Sqrt(d)
+}
+
+func (w weightedEuclidean) AxisDistance(axis int, delta float64) float64 {
+	return math.Sqrt(w[axis]) * delta
+}
+
+// finds distance squared between a and b
+func distSq(a, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		delta := b[i] - a[i]
+		d += delta * delta
+	}
+	return d
+}
@@ -0,0 +1,89 @@
+package kdtree
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestMetric_Distance(t *testing.T) {
+	a, b := Point{1, 2, 3}, Point{4, -2, 3}
+	tests := []struct {
+		name string
+		m    Metric
+		want float64
+	}{
+		{"euclidean", Euclidean, 5},
+		{"manhattan", Manhattan, 7},
+		{"chebyshev", Chebyshev, 4},
+		{"minkowski1", Minkowski(1), 7},
+		{"minkowski2", Minkowski(2), 5},
+		{"minkowski3", Minkowski(3), math.Cbrt(27 + 64)},
+		{"minkowskiInf", Minkowski(math.Inf(1)), 4},
+		{"weighted", WeightedEuclidean(4, 1, 100), math.Sqrt(36 + 16)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Distance(a, b); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Distance() = %v, want %v", got, tt.want)
+			}
+			if got := tt.m.Distance(b, a); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Distance() reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetric_AxisDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, m := range testMetrics(4) {
+		for i := 0; i < 1000; i++ {
+			a, b := randomPoint(rng, 4, -1, 1), randomPoint(rng, 4, -1, 1)
+			for axis := range a {
+				if lb := m.AxisDistance(axis, math.Abs(a[axis]-b[axis])); lb > m.Distance(a, b)+1e-12 {
+					t.Fatalf("%s: AxisDistance(%d) = %v is more than Distance(%v, %v) = %v", name, axis, lb, a, b, m.Distance(a, b))
+				}
+			}
+		}
+	}
+}
+
+func TestMinkowski_Invalid(t *testing.T) {
+	for _, p := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Minkowski(%v) did not panic", p)
+				}
+			}()
+			Minkowski(p)
+		}()
+	}
+}
+
+// trees with different metrics can be searched at the same time.
+func TestTree_Concurrent(t *testing.T) {
+	pts := []Point{{3, 3}, {4, 0}, {-10, 10}}
+	q := Point{0, 0}
+	trees := map[*Tree]Point{
+		NewTree(append([]Point(nil), pts...), Euclidean): {4, 0},
+		NewTree(append([]Point(nil), pts...), Chebyshev): {3, 3},
+	}
+	wg := sync.WaitGroup{}
+	for tree, want := range trees {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(tree *Tree, want Point) {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					if got := tree.NearestNeighbor(q).Point; got[0] != want[0] || got[1] != want[1] {
+						t.Errorf("NearestNeighbor() = %v, want %v", got, want)
+						return
+					}
+				}
+			}(tree, want)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package kdtree
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the file into memory read only, returning the function which
+// unmaps it.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size == 0 || int64(int(size)) != size {
+		// can't map nothing, or something too big to address.
+		return nil, nil, nil
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
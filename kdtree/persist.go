@@ -0,0 +1,193 @@
+package kdtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"unsafe"
+)
+
+// The errors for files which cannot be loaded as a FlatTree.
+var (
+	ErrNotFlatTree        = errors.New("kdtree: not a flat tree file")
+	ErrUnsupportedVersion = errors.New("kdtree: unsupported flat tree file version")
+	ErrCorrupt            = errors.New("kdtree: corrupt flat tree file")
+)
+
+// The flat tree file format, all little endian:
+//
+//	magic   [4]byte "KDTF"
+//	version uint32
+//	dims    uint32
+//	unused  uint32
+//	n       uint64
+//	coords  [n*dims]float64, the points in tree order
+//	index   [n]int32, the index in the items of each point
+//	crc     uint32, CRC-32 (IEEE) of everything before it
+//
+// The coordinates start 8 byte aligned, so a mapped file can be used as is.
+const (
+	flatMagic      = "KDTF"
+	flatVersion    = 1
+	flatHeaderSize = 24
+)
+
+// WriteTo writes the tree to w in a binary format which ReadFlatTree and
+// OpenFlatTree load. The metric is not written.
+func (t *FlatTree) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(cw, crc))
+	buf := make([]byte, 8)
+
+	header := make([]byte, flatHeaderSize)
+	copy(header, flatMagic)
+	binary.LittleEndian.PutUint32(header[4:], flatVersion)
+	binary.LittleEndian.PutUint32(header[8:], uint32(t.dims))
+	binary.LittleEndian.PutUint64(header[16:], uint64(t.Len()))
+	bw.Write(header)
+	for _, v := range t.coords {
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		bw.Write(buf)
+	}
+	for _, i := range t.index {
+		binary.LittleEndian.PutUint32(buf, uint32(i))
+		bw.Write(buf[:4])
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+
+	binary.LittleEndian.PutUint32(buf, crc.Sum32())
+	_, err := cw.Write(buf[:4])
+	return cw.n, err
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// ReadFlatTree loads a tree written by FlatTree.WriteTo from r. The tree is
+// searched with the metric m (Euclidean if nil).
+func ReadFlatTree(r io.Reader, m Metric) (*FlatTree, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFlatTree(data, m)
+}
+
+// OpenFlatTree loads the tree written by FlatTree.WriteTo in the file. Where
+// possible the file is memory mapped and searched in place, without being
+// copied or decoded, but it is still read once to check its checksum. The
+// tree must be closed with Close when no longer needed.
+func OpenFlatTree(filename string, m Metric) (*FlatTree, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, unmap, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+	if unmap == nil {
+		// not mapped, so read it.
+		return ReadFlatTree(f, m)
+	}
+	t, err := decodeFlatTree(data, m)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	t.unmap = unmap
+	return t, nil
+}
+
+// Close releases the memory of a tree loaded by OpenFlatTree, after which it
+// must not be used. It does nothing for other trees.
+func (t *FlatTree) Close() error {
+	if t.unmap == nil {
+		return nil
+	}
+	unmap := t.unmap
+	*t = FlatTree{Metric: t.Metric}
+	return unmap()
+}
+
+// decodeFlatTree makes a tree from the file data, using the data's memory for
+// the tree if it is suitably aligned.
+func decodeFlatTree(data []byte, m Metric) (*FlatTree, error) {
+	if len(data) < len(flatMagic) || string(data[:len(flatMagic)]) != flatMagic {
+		return nil, ErrNotFlatTree
+	}
+	if len(data) < flatHeaderSize+4 {
+		return nil, fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+	if v := binary.LittleEndian.Uint32(data[4:]); v != flatVersion {
+		return nil, fmt.Errorf("%w: version %d, want %d", ErrUnsupportedVersion, v, flatVersion)
+	}
+	dims := uint64(binary.LittleEndian.Uint32(data[8:]))
+	n := binary.LittleEndian.Uint64(data[16:])
+	if n > math.MaxInt32 || dims > uint64(len(data)) || n > 0 && dims == 0 {
+		return nil, fmt.Errorf("%w: bad size", ErrCorrupt)
+	}
+	if size := flatHeaderSize + n*dims*8 + n*4 + 4; size != uint64(len(data)) {
+		return nil, fmt.Errorf("%w: size is %d bytes, want %d", ErrCorrupt, len(data), size)
+	}
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+
+	if m == nil {
+		m = Euclidean
+	}
+	t := &FlatTree{Metric: m, dims: int(dims)}
+	if n == 0 {
+		return t, nil
+	}
+	coords := body[flatHeaderSize : flatHeaderSize+n*dims*8]
+	index := body[flatHeaderSize+n*dims*8:]
+	if littleEndian && uintptr(unsafe.Pointer(&coords[0]))%8 == 0 {
+		t.coords = unsafe.Slice((*float64)(unsafe.Pointer(&coords[0])), n*dims)
+		t.index = unsafe.Slice((*int32)(unsafe.Pointer(&index[0])), n)
+	} else {
+		t.coords = make([]float64, n*dims)
+		for i := range t.coords {
+			t.coords[i] = math.Float64frombits(binary.LittleEndian.Uint64(coords[8*i:]))
+		}
+		t.index = make([]int32, n)
+		for i := range t.index {
+			t.index[i] = int32(binary.LittleEndian.Uint32(index[4*i:]))
+		}
+	}
+
+	for _, i := range t.index {
+		if i < 0 || uint64(i) >= n {
+			return nil, fmt.Errorf("%w: bad index %d", ErrCorrupt, i)
+		}
+	}
+	return t, nil
+}
+
+// littleEndian is true if the machine stores numbers little endian, as in
+// the files.
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
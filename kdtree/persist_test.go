@@ -0,0 +1,131 @@
+package kdtree
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFlatTree_WriteTo(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pts := randomPoints(rng, 1000, 3)
+	tree := NewFlatTree(pts, Manhattan, 1)
+
+	buf := &bytes.Buffer{}
+	n, err := tree.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", n, buf.Len())
+	}
+	filename := filepath.Join(t.TempDir(), "tree.kdt")
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadFlatTree(bytes.NewReader(buf.Bytes()), Manhattan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := OpenFlatTree(filename, Manhattan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opened.Close()
+
+	for name, loaded := range map[string]*FlatTree{"read": read, "opened": opened} {
+		if loaded.Len() != tree.Len() || loaded.Dims() != tree.Dims() {
+			t.Fatalf("%s: Len(), Dims() = %d, %d", name, loaded.Len(), loaded.Dims())
+		}
+		for i := 0; i < 100; i++ {
+			q := randomPoint(rng, 3, 0, 100)
+			if got, want := loaded.NearestKNeighbors(5, q), tree.NearestKNeighbors(5, q); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: NearestKNeighbors(%v) = %v, want %v", name, q, got, want)
+			}
+		}
+	}
+
+	if err := opened.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, ok := opened.NearestNeighbor(Point{1, 2, 3}); ok {
+		t.Error("closed tree found a point")
+	}
+}
+
+func TestFlatTree_WriteTo_Empty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if _, err := NewFlatTree(nil, nil, 1).WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := ReadFlatTree(buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Len() != 0 {
+		t.Errorf("Len() = %d", tree.Len())
+	}
+}
+
+func TestReadFlatTree_Errors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	NewFlatTree(randomPoints(rand.New(rand.NewSource(1)), 10, 2), nil, 1).WriteTo(buf)
+	data := buf.Bytes()
+	modified := func(at int, b byte) []byte {
+		d := append([]byte(nil), data...)
+		d[at] ^= b
+		return d
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrNotFlatTree},
+		{"magic", modified(0, 1), ErrNotFlatTree},
+		{"version", modified(4, 2), ErrUnsupportedVersion},
+		{"truncated header", data[:10], ErrCorrupt},
+		{"truncated", data[:len(data)-1], ErrCorrupt},
+		{"size", modified(16, 1), ErrCorrupt},
+		{"coords", modified(flatHeaderSize+5, 1), ErrCorrupt},
+		{"checksum", modified(len(data)-1, 1), ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadFlatTree(bytes.NewReader(tt.data), nil); !errors.Is(err, tt.want) {
+				t.Errorf("ReadFlatTree() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	filename := filepath.Join(t.TempDir(), "bad.kdt")
+	os.WriteFile(filename, modified(len(data)-1, 1), 0644)
+	if _, err := OpenFlatTree(filename, nil); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("OpenFlatTree() error = %v, want %v", err, ErrCorrupt)
+	}
+	if _, err := OpenFlatTree(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("OpenFlatTree() of missing file did not fail")
+	}
+}
+
+func BenchmarkOpenFlatTree(b *testing.B) {
+	tree := NewFlatTree(randomPoints(rand.New(rand.NewSource(1)), 1000000, 3), nil, 1)
+	filename := filepath.Join(b.TempDir(), "tree.kdt")
+	f, _ := os.Create(filename)
+	tree.WriteTo(f)
+	f.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		t, err := OpenFlatTree(filename, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		t.Close()
+	}
+}
@@ -0,0 +1,93 @@
+package kdtree
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// the relative error bounds of orient and inCircle in float64, from Shewchuk's
+// "Adaptive Precision Floating-Point Arithmetic and Fast Robust Geometric
+// Predicates". If a result is bigger than this times the sum of the
+// magnitudes of its terms, its sign is right. Otherwise it is computed again
+// exactly.
+const (
+	epsilon          = 1.0 / (1 << 53)
+	orientErrBound   = (3 + 16*epsilon) * epsilon
+	inCircleErrBound = (10 + 96*epsilon) * epsilon
+)
+
+// orient gets 1 if the triangle a, b, c is counterclockwise (with the y axis
+// up), -1 if it is clockwise, and 0 if the points are on a line. The result is
+// exact.
+func orient(a, b, c mgl64.Vec2) int {
+	l := (a.X() - c.X()) * (b.Y() - c.Y())
+	r := (a.Y() - c.Y()) * (b.X() - c.X())
+	if det := l - r; math.Abs(det) > orientErrBound*(math.Abs(l)+math.Abs(r)) {
+		return sign(det)
+	}
+
+	x := exact(a, b, c)
+	ac := [2]*big.Rat{sub(x[0], x[4]), sub(x[1], x[5])}
+	bc := [2]*big.Rat{sub(x[2], x[4]), sub(x[3], x[5])}
+	return mul(ac[0], bc[1]).Cmp(mul(ac[1], bc[0]))
+}
+
+// inCircle gets 1 if d is inside the circumcircle of the counterclockwise
+// triangle a, b, c, -1 if it is outside, and 0 if it is on the circle. The
+// result is exact.
+func inCircle(a, b, c, d mgl64.Vec2) int {
+	ad, bd, cd := a.Sub(d), b.Sub(d), c.Sub(d)
+	bc, cb := bd.X()*cd.Y(), cd.X()*bd.Y()
+	ca, ac := cd.X()*ad.Y(), ad.X()*cd.Y()
+	ab, ba := ad.X()*bd.Y(), bd.X()*ad.Y()
+	alift, blift, clift := ad.Dot(ad), bd.Dot(bd), cd.Dot(cd)
+	det := alift*(bc-cb) + blift*(ca-ac) + clift*(ab-ba)
+	permanent := (math.Abs(bc)+math.Abs(cb))*alift +
+		(math.Abs(ca)+math.Abs(ac))*blift +
+		(math.Abs(ab)+math.Abs(ba))*clift
+	if math.Abs(det) > inCircleErrBound*permanent {
+		return sign(det)
+	}
+
+	// the rows of the determinant: x, y and x^2+y^2 of a, b and c relative
+	// to d.
+	x := exact(a, b, c, d)
+	var rows [3][3]*big.Rat
+	for i := range rows {
+		dx, dy := sub(x[2*i], x[6]), sub(x[2*i+1], x[7])
+		rows[i] = [3]*big.Rat{dx, dy, new(big.Rat).Add(mul(dx, dx), mul(dy, dy))}
+	}
+	sum := new(big.Rat)
+	for i, row := range rows {
+		r1, r2 := rows[(i+1)%3], rows[(i+2)%3]
+		minor := sub(mul(r1[0], r2[1]), mul(r2[0], r1[1]))
+		sum.Add(sum, mul(minor, row[2]))
+	}
+	return sum.Sign()
+}
+
+// exact gets the coordinates of the points as exact rationals, x then y of
+// each.
+func exact(points ...mgl64.Vec2) []*big.Rat {
+	x := make([]*big.Rat, 0, 2*len(points))
+	for _, p := range points {
+		x = append(x, new(big.Rat).SetFloat64(p.X()), new(big.Rat).SetFloat64(p.Y()))
+	}
+	return x
+}
+
+func sub(a, b *big.Rat) *big.Rat { return new(big.Rat).Sub(a, b) }
+
+func mul(a, b *big.Rat) *big.Rat { return new(big.Rat).Mul(a, b) }
+
+func sign(x float64) int {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
@@ -0,0 +1,215 @@
+package kdtree
+
+import (
+	"math"
+	"sort"
+)
+
+// Neighbor is a node found by a search, with its distance from the search
+// point.
+type Neighbor struct {
+	*Node
+	Dist float64
+}
+
+// Radius finds the nodes within distance r (inclusive) of searchPt, nearest
+// first. If limit > 0, the search stops once limit nodes are found, and those
+// are not necessarily the nearest ones.
+func (t *Tree) Radius(searchPt Point, r float64, limit int) (found []Neighbor) {
+	t.RadiusFunc(searchPt, r, func(n Neighbor) bool {
+		found = append(found, n)
+		return limit <= 0 || len(found) < limit
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].Dist < found[j].Dist })
+	return
+}
+
+// RadiusFunc calls fn for each node within distance r (inclusive) of
+// searchPt, in no particular order, until fn returns false.
+func (t *Tree) RadiusFunc(searchPt Point, r float64, fn func(Neighbor) bool) {
+	radiusSearch(t.Root, searchPt, r, t.metric(), fn)
+}
+
+// does actual radius search. returns false if the search was stopped.
+func radiusSearch(root *Node, searchPt Point, r float64, m Metric, fn func(Neighbor) bool) bool {
+	if root == nil {
+		return true
+	}
+
+	if dist := m.Distance(root.Point, searchPt); dist <= r {
+		if !fn(Neighbor{root, dist}) {
+			return false
+		}
+	}
+
+	// the side of the splitting plane holding searchPt always needs to be
+	// searched, the other only if the plane is within r.
+	near, far := root.Left, root.Right
+	if searchPt[root.Axis] > root.Point[root.Axis] {
+		near, far = far, near
+	}
+	if !radiusSearch(near, searchPt, r, m, fn) {
+		return false
+	}
+	if m.AxisDistance(root.Axis, math.Abs(root.Point[root.Axis]-searchPt[root.Axis])) <= r {
+		return radiusSearch(far, searchPt, r, m, fn)
+	}
+	return true
+}
+
+// Box finds the nodes inside the axis-aligned box from min to max
+// (inclusive), with their distances from the center of the box, nearest
+// first. If limit > 0, the search stops once limit nodes are found, and those
+// are not necessarily the nearest ones.
+func (t *Tree) Box(min, max Point, limit int) (found []Neighbor) {
+	t.BoxFunc(min, max, func(n Neighbor) bool {
+		found = append(found, n)
+		return limit <= 0 || len(found) < limit
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].Dist < found[j].Dist })
+	return
+}
+
+// BoxFunc calls fn for each node inside the axis-aligned box from min to max
+// (inclusive), with its distance from the center of the box, in no
+// particular order, until fn returns false.
+func (t *Tree) BoxFunc(min, max Point, fn func(Neighbor) bool) {
+	m := t.metric()
+	center := make(Point, len(min))
+	for i := range center {
+		center[i] = (min[i] + max[i]) / 2
+	}
+	boxSearch(t.Root, min, max, func(n *Node) bool {
+		return fn(Neighbor{n, m.Distance(n.Point, center)})
+	})
+}
+
+// does actual box search. returns false if the search was stopped.
+func boxSearch(root *Node, min, max Point, fn func(*Node) bool) bool {
+	if root == nil {
+		return true
+	}
+
+	if inside(root.Point, min, max) && !fn(root) {
+		return false
+	}
+
+	// points equal to the split value can be on either side.
+	split := root.Point[root.Axis]
+	if min[root.Axis] <= split && !boxSearch(root.Left, min, max, fn) {
+		return false
+	}
+	if max[root.Axis] >= split {
+		return boxSearch(root.Right, min, max, fn)
+	}
+	return true
+}
+
+// Radius finds the points within distance r (inclusive) of searchPt, nearest
+// first. If limit > 0, the search stops once limit points are found, and
+// those are not necessarily the nearest ones.
+func (t *FlatTree) Radius(searchPt Point, r float64, limit int) (found []FlatNeighbor) {
+	t.RadiusFunc(searchPt, r, func(n FlatNeighbor) bool {
+		found = append(found, n)
+		return limit <= 0 || len(found) < limit
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].Dist < found[j].Dist })
+	return
+}
+
+// RadiusFunc calls fn for each point within distance r (inclusive) of
+// searchPt, in no particular order, until fn returns false.
+func (t *FlatTree) RadiusFunc(searchPt Point, r float64, fn func(FlatNeighbor) bool) {
+	t.radiusSearch(0, t.Len(), 0, searchPt, r, func(n flatNeigh) bool {
+		return fn(t.neighbor(n))
+	})
+}
+
+// does actual radius search of the subtree at [lo,hi) splitting on axis.
+// returns false if the search was stopped.
+func (t *FlatTree) radiusSearch(lo, hi, axis int, searchPt Point, r float64, fn func(flatNeigh) bool) bool {
+	if lo == hi {
+		return true
+	}
+	mid := lo + (hi-lo)/2
+	p := t.point(mid)
+	next := (axis + 1) % t.dims
+
+	if dist := t.Metric.Distance(p, searchPt); dist <= r {
+		if !fn(flatNeigh{mid, dist}) {
+			return false
+		}
+	}
+
+	nearLo, nearHi, farLo, farHi := lo, mid, mid+1, hi
+	if searchPt[axis] > p[axis] {
+		nearLo, nearHi, farLo, farHi = farLo, farHi, nearLo, nearHi
+	}
+	if !t.radiusSearch(nearLo, nearHi, next, searchPt, r, fn) {
+		return false
+	}
+	if t.Metric.AxisDistance(axis, math.Abs(p[axis]-searchPt[axis])) <= r {
+		return t.radiusSearch(farLo, farHi, next, searchPt, r, fn)
+	}
+	return true
+}
+
+// Box finds the points inside the axis-aligned box from min to max
+// (inclusive), with their distances from the center of the box, nearest
+// first. If limit > 0, the search stops once limit points are found, and
+// those are not necessarily the nearest ones.
+func (t *FlatTree) Box(min, max Point, limit int) (found []FlatNeighbor) {
+	t.BoxFunc(min, max, func(n FlatNeighbor) bool {
+		found = append(found, n)
+		return limit <= 0 || len(found) < limit
+	})
+	sort.Slice(found, func(i, j int) bool { return found[i].Dist < found[j].Dist })
+	return
+}
+
+// BoxFunc calls fn for each point inside the axis-aligned box from min to
+// max (inclusive), with its distance from the center of the box, in no
+// particular order, until fn returns false.
+func (t *FlatTree) BoxFunc(min, max Point, fn func(FlatNeighbor) bool) {
+	center := make(Point, len(min))
+	for i := range center {
+		center[i] = (min[i] + max[i]) / 2
+	}
+	t.boxSearch(0, t.Len(), 0, min, max, func(pos int) bool {
+		return fn(t.neighbor(flatNeigh{pos, t.Metric.Distance(t.point(pos), center)}))
+	})
+}
+
+// does actual box search of the subtree at [lo,hi) splitting on axis.
+// returns false if the search was stopped.
+func (t *FlatTree) boxSearch(lo, hi, axis int, min, max Point, fn func(pos int) bool) bool {
+	if lo == hi {
+		return true
+	}
+	mid := lo + (hi-lo)/2
+	p := t.point(mid)
+	next := (axis + 1) % t.dims
+
+	if inside(p, min, max) && !fn(mid) {
+		return false
+	}
+
+	// points equal to the split value can be on either side.
+	if min[axis] <= p[axis] && !t.boxSearch(lo, mid, next, min, max, fn) {
+		return false
+	}
+	if max[axis] >= p[axis] {
+		return t.boxSearch(mid+1, hi, next, min, max, fn)
+	}
+	return true
+}
+
+// inside tests if p is inside the box from min to max (inclusive).
+func inside(p, min, max Point) bool {
+	for i, v := range p {
+		if v < min[i] || v > max[i] {
+			return false
+		}
+	}
+	return true
+}
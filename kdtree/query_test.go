@@ -0,0 +1,133 @@
+package kdtree
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestTree_Radius(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{1, 2, 3, 8} {
+		for name, m := range testMetrics(dims) {
+			t.Run(fmt.Sprintf("%s %dd", name, dims), func(t *testing.T) {
+				pts := randomPoints(rng, 500, dims)
+				tree := NewTree(append([]Point(nil), pts...), m)
+				for i := 0; i < 50; i++ {
+					q := randomPoint(rng, dims, -10, 110)
+					dists := bruteForce(pts, q, m)
+					r := dists[rng.Intn(50)]
+					want := 0
+					for _, d := range dists {
+						if d <= r {
+							want++
+						}
+					}
+
+					found := tree.Radius(q, r, 0)
+					if len(found) != want {
+						t.Fatalf("Radius(%v, %v) found %d nodes, want %d", q, r, len(found), want)
+					}
+					for j, n := range found {
+						if n.Dist != dists[j] || m.Distance(n.Point, q) != n.Dist {
+							t.Fatalf("Radius(%v, %v)[%d] at %v, want %v", q, r, j, n.Dist, dists[j])
+						}
+					}
+
+					if limited := tree.Radius(q, r, 3); len(limited) != 3 && len(limited) != want {
+						t.Fatalf("Radius(%v, %v) with limit 3 found %d nodes", q, r, len(limited))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTree_Box(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, dims := range []int{1, 2, 3, 8} {
+		t.Run(fmt.Sprintf("%dd", dims), func(t *testing.T) {
+			// integer coordinates, so that points lie on the box's faces.
+			pts := make([]Point, 500)
+			for i := range pts {
+				pts[i] = make(Point, dims)
+				for j := range pts[i] {
+					pts[i][j] = float64(rng.Intn(10))
+				}
+			}
+			tree := NewTree(append([]Point(nil), pts...), nil)
+			for i := 0; i < 50; i++ {
+				min, max := make(Point, dims), make(Point, dims)
+				for j := range min {
+					min[j] = float64(rng.Intn(10))
+					max[j] = min[j] + float64(rng.Intn(6))
+				}
+				want := 0
+				for _, p := range pts {
+					if inBox(p, min, max) {
+						want++
+					}
+				}
+
+				center := make(Point, dims)
+				for j := range center {
+					center[j] = (min[j] + max[j]) / 2
+				}
+				found := tree.Box(min, max, 0)
+				if len(found) != want {
+					t.Fatalf("Box(%v, %v) found %d nodes, want %d", min, max, len(found), want)
+				}
+				for k, n := range found {
+					if !inBox(n.Point, min, max) {
+						t.Fatalf("Box(%v, %v) found %v", min, max, n.Point)
+					}
+					if d := Euclidean.Distance(n.Point, center); n.Dist != d {
+						t.Fatalf("Box(%v, %v) found %v at distance %g, want %g", min, max, n.Point, n.Dist, d)
+					}
+					if k > 0 && n.Dist < found[k-1].Dist {
+						t.Fatalf("Box(%v, %v) is not sorted by distance", min, max)
+					}
+				}
+			}
+		})
+	}
+}
+
+func inBox(p, min, max Point) bool {
+	for i := range p {
+		if p[i] < min[i] || p[i] > max[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTree_QueryFuncStop(t *testing.T) {
+	pts := randomPoints(rand.New(rand.NewSource(1)), 100, 2)
+	tree := NewTree(pts, Euclidean)
+
+	n := 0
+	tree.RadiusFunc(Point{50, 50}, 1000, func(Neighbor) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("RadiusFunc() called fn %d times after it returned false, want 5", n)
+	}
+
+	n = 0
+	tree.BoxFunc(Point{0, 0}, Point{100, 100}, func(Neighbor) bool {
+		n++
+		return n < 5
+	})
+	if n != 5 {
+		t.Errorf("BoxFunc() called fn %d times after it returned false, want 5", n)
+	}
+
+	if found := tree.Box(Point{0, 0}, Point{100, 100}, 7); len(found) != 7 {
+		t.Errorf("Box() with limit 7 found %d nodes", len(found))
+	}
+	if found := (&Tree{Metric: Euclidean}).Radius(Point{0, 0}, 1, 0); len(found) != 0 {
+		t.Errorf("Radius() in empty tree = %v", found)
+	}
+}
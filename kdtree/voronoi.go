@@ -1,63 +1,284 @@
-package main
+package kdtree
 
 import (
 	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
+	"image/jpeg"
 	"image/png"
-	"io/ioutil"
+	"io"
 	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/lucasb-eyer/go-colorful"
 	"github.com/quillaja/goutil/rand"
 )
 
-// Voronoi creates a Voronoi plot of the given points.
-func Voronoi(points []mgl64.Vec2, width, height int, d DistMetric, filename string) {
+// VoronoiOptions control how a Voronoi diagram is drawn. The zero value draws
+// Euclidean cells of random colors, without borders, with each seed point
+// marked by a black pixel.
+type VoronoiOptions struct {
+	// Metric measures the distances to the seeds. Euclidean if nil.
+	Metric Metric
 
-	// 0. set distance metric
-	Dist = d
+	// Color gets the color of the cell of the i'th seed point. If nil, the
+	// cells get random colors.
+	Color func(i int) color.Color
 
-	// 1. use given point set to build tree and assign a random
-	// color to each point
-	root := BuildTree(points)
-	PreOrderTraversal(root, func(node *Node) {
-		node.Data = colorful.Hsv(rand.Float64NM(0, 360), 1, 1)
-	})
+	// Border, if > 0, draws the borders between cells, on the pixels where
+	// the distances to the nearest and second nearest seeds differ by less
+	// than Border.
+	Border      float64
+	BorderColor color.Color // black if nil
+
+	Marker      Marker
+	MarkerSize  int         // radius of the marker in pixels
+	MarkerColor color.Color // black if nil
+}
+
+// Marker is the shape drawn at each seed point of a Voronoi diagram.
+type Marker int
+
+// the marker shapes
+const (
+	MarkerDot Marker = iota
+	MarkerCircle
+	MarkerCross
+	MarkerNone
+)
+
+// ImageFormat is a file format for a Voronoi diagram.
+type ImageFormat int
+
+// the formats a Voronoi diagram can be written in
+const (
+	PNG ImageFormat = iota
+	JPEG
+	// SVG embeds the cells as a PNG image, with the markers drawn as shapes
+	// over it.
+	SVG
+)
+
+// Voronoi creates a Voronoi plot of the given points, using the metric m,
+// and writes it to filename. The format is JPEG or SVG for filenames ending
+// in .jpg, .jpeg or .svg, else PNG.
+func Voronoi(points []mgl64.Vec2, width, height int, m Metric, filename string) error {
+	if m == nil {
+		return errors.New("kdtree: no metric for voronoi plot")
+	}
+	format := PNG
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		format = JPEG
+	case ".svg":
+		format = SVG
+	}
+
+	// draw it all before creating the file, so errors don't leave one behind.
+	var b bytes.Buffer
+	if err := WriteVoronoi(&b, format, points, width, height, VoronoiOptions{Metric: m}); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b.Bytes(), 0644)
+}
+
+// VoronoiImage draws the Voronoi diagram of the points, where each pixel has
+// the color of the cell of its nearest point.
+func VoronoiImage(points []mgl64.Vec2, width, height int, opts VoronoiOptions) (*image.RGBA, error) {
+	img, err := voronoiCells(points, width, height, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Marker != MarkerNone {
+		c := colorOr(opts.MarkerColor, color.Black)
+		for _, p := range points {
+			drawMarker(img, p, opts.Marker, opts.MarkerSize, c)
+		}
+	}
+	return img, nil
+}
+
+// WriteVoronoi draws the Voronoi diagram of the points (see VoronoiImage) and
+// writes it to w in the format.
+func WriteVoronoi(w io.Writer, format ImageFormat, points []mgl64.Vec2, width, height int, opts VoronoiOptions) error {
+	switch format {
+	case PNG, JPEG:
+		img, err := VoronoiImage(points, width, height, opts)
+		if err != nil {
+			return err
+		}
+		if format == JPEG {
+			return jpeg.Encode(w, img, nil)
+		}
+		return png.Encode(w, img)
+	case SVG:
+		img, err := voronoiCells(points, width, height, opts)
+		if err != nil {
+			return err
+		}
+		return writeSVG(w, img, points, opts)
+	}
+	return fmt.Errorf("kdtree: unknown image format %d", format)
+}
+
+// voronoiCells checks the arguments, then draws the cells (and borders) of
+// the diagram.
+func voronoiCells(points []mgl64.Vec2, width, height int, opts VoronoiOptions) (*image.RGBA, error) {
+	if len(points) == 0 {
+		return nil, errors.New("kdtree: no points for voronoi plot")
+	}
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("kdtree: voronoi plot size must be positive")
+	}
+	if opts.Marker < MarkerDot || opts.Marker > MarkerNone {
+		return nil, fmt.Errorf("kdtree: unknown marker %d", opts.Marker)
+	}
+	if opts.MarkerSize < 0 {
+		return nil, errors.New("kdtree: marker size must not be negative")
+	}
+
+	// 1. use given point set to build tree and assign a color to each point.
+	items := make([]Item[color.RGBA], len(points))
+	for i, p := range points {
+		var c color.Color
+		if opts.Color != nil {
+			if c = opts.Color(i); c == nil {
+				return nil, fmt.Errorf("kdtree: no color for point %d", i)
+			}
+		} else {
+			c = colorful.Hsv(rand.Float64NM(0, 360), 1, 1)
+		}
+		items[i] = Item[color.RGBA]{Vec2Point(p), color.RGBAModel.Convert(c).(color.RGBA)}
+	}
+	index := NewIndex(items, opts.Metric, 1)
 
 	// 2. build image pixel by pixel. color pixel based on the
-	// nearest neighbor in the tree. Run NN search and image draw in
-	// a gofunc to improve performance.
-	wg := sync.WaitGroup{}
+	// nearest neighbor in the tree.
+	border := color.RGBAModel.Convert(colorOr(opts.BorderColor, color.Black)).(color.RGBA)
+	return renderVoronoi(index, width, height, opts.Border, border, runtime.GOMAXPROCS(0)), nil
+}
+
+// renderVoronoi makes an image where each pixel has the color of the item
+// nearest to it, or borderColor if the distances to the 2 nearest items
+// differ by less than border. The rows of the image are shared among workers
+// goroutines, which write their pixels directly.
+func renderVoronoi(index *Index[color.RGBA], width, height int, border float64, borderColor color.RGBA, workers int) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			wg.Add(1)
-			go func(x, y int) {
-				nn := NearestNeighbor(root, mgl64.Vec2{float64(x), float64(y)})
-				img.Set(x, y, nn.Data.(color.Color))
-				wg.Done()
-			}(x, y)
-		}
+	tree := index.tree
+	var nextRow int64 = -1
+
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := Point{0, 0}
+			rowHint := -1
+			bests := newBestK[flatNeigh](2, tree.Len())
+			buf := bests.items
+			for {
+				y := int(atomic.AddInt64(&nextRow, 1))
+				if y >= height {
+					return
+				}
+				q[1] = float64(y)
+
+				// neighboring pixels mostly have the same nearest neighbor, so
+				// each pixel's search starts from the last pixel's.
+				hint := rowHint
+				pix := img.Pix[y*img.Stride:]
+				for x := 0; x < width; x++ {
+					q[0] = float64(x)
+					c := borderColor
+					if border > 0 {
+						bests.items = buf[:0]
+						tree.knnSearch(0, tree.Len(), 0, q, bests)
+						buf = bests.sorted()
+						if len(buf) < 2 || buf[1].dist-buf[0].dist >= border {
+							c = index.items[tree.index[buf[0].pos]].Value
+						}
+					} else {
+						hint = tree.nearestFrom(q, hint).pos
+						if x == 0 {
+							rowHint = hint
+						}
+						c = index.items[tree.index[hint]].Value
+					}
+					pix[4*x], pix[4*x+1], pix[4*x+2], pix[4*x+3] = c.R, c.G, c.B, c.A
+				}
+			}
+		}()
 	}
 	wg.Wait()
+	return img
+}
 
-	// 2.5 draw point set
-	for _, p := range points {
-		img.Set(int(math.Round(p.X())), int(math.Round(p.Y())), color.Black)
+// drawMarker draws a marker of radius size at p.
+func drawMarker(img *image.RGBA, p mgl64.Vec2, m Marker, size int, c color.Color) {
+	cx, cy := int(math.Round(p.X())), int(math.Round(p.Y()))
+	for dy := -size; dy <= size; dy++ {
+		for dx := -size; dx <= size; dx++ {
+			var on bool
+			switch r := math.Hypot(float64(dx), float64(dy)); m {
+			case MarkerDot:
+				on = r <= float64(size)+0.5
+			case MarkerCircle:
+				on = math.Abs(r-float64(size)) <= 0.5
+			case MarkerCross:
+				on = dx == 0 || dy == 0
+			}
+			if on {
+				img.Set(cx+dx, cy+dy, c)
+			}
+		}
 	}
+}
 
-	// 3. write image to disk
-	data := []byte{}
-	buf := bytes.NewBuffer(data)
-	err := png.Encode(buf, img)
-	if err != nil {
-		panic(err)
+// writeSVG writes an SVG of the cells in img with the markers of the points
+// drawn over it.
+func writeSVG(w io.Writer, img *image.RGBA, points []mgl64.Vec2, opts VoronoiOptions) error {
+	var cells bytes.Buffer
+	if err := png.Encode(&cells, img); err != nil {
+		return err
 	}
-	err = ioutil.WriteFile(filename, buf.Bytes(), 0644)
-	if err != nil {
-		panic(err)
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(&b, "<image width=\"%d\" height=\"%d\" href=\"data:image/png;base64,%s\"/>\n", width, height, base64.StdEncoding.EncodeToString(cells.Bytes()))
+
+	r, g, bl, _ := color.RGBAModel.Convert(colorOr(opts.MarkerColor, color.Black)).RGBA()
+	fill := fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, bl>>8)
+	size := float64(opts.MarkerSize) + 0.5
+	for _, p := range points {
+		x, y := math.Round(p.X()), math.Round(p.Y())
+		switch opts.Marker {
+		case MarkerDot:
+			fmt.Fprintf(&b, "<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"%s\"/>\n", x+0.5, y+0.5, size, fill)
+		case MarkerCircle:
+			fmt.Fprintf(&b, "<circle cx=\"%g\" cy=\"%g\" r=\"%g\" fill=\"none\" stroke=\"%s\"/>\n", x+0.5, y+0.5, size-0.5, fill)
+		case MarkerCross:
+			fmt.Fprintf(&b, "<path d=\"M%g %gh%gM%g %gv%g\" stroke=\"%s\"/>\n", x+0.5-size, y+0.5, 2*size, x+0.5, y+0.5-size, 2*size, fill)
+		}
+	}
+	b.WriteString("</svg>\n")
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// colorOr gets c, or def if c is nil.
+func colorOr(c, def color.Color) color.Color {
+	if c == nil {
+		return def
 	}
+	return c
 }
@@ -0,0 +1,218 @@
+package kdtree
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// randomColorIndex makes an index of n random 2d points in [0,100) with
+// random colors.
+func randomColorIndex(rng *rand.Rand, n int, m Metric) *Index[color.RGBA] {
+	items := make([]Item[color.RGBA], n)
+	for i, p := range randomPoints(rng, n, 2) {
+		items[i] = Item[color.RGBA]{p, color.RGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), 255}}
+	}
+	return NewIndex(items, m, 1)
+}
+
+// voronoiPerPixel stands in for the renderer Voronoi had before
+// renderVoronoi, to compare with it. That one started a goroutine per pixel,
+// each searching a pointer tree from scratch. It can't be run as it was, since
+// it read the metric from a global and the colors from the nodes, so this
+// keeps its goroutine per pixel but searches the index.
+func voronoiPerPixel(index *Index[color.RGBA], width, height int) *image.RGBA {
+	wg := sync.WaitGroup{}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			wg.Add(1)
+			go func(x, y int) {
+				nn, _ := index.Nearest(Point{float64(x), float64(y)})
+				img.Set(x, y, nn.Value)
+				wg.Done()
+			}(x, y)
+		}
+	}
+	wg.Wait()
+	return img
+}
+
+func TestRenderVoronoi(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for name, m := range testMetrics(2) {
+		t.Run(name, func(t *testing.T) {
+			index := randomColorIndex(rng, 30, m)
+			pts := make([]Point, index.Len())
+			for i := range pts {
+				pts[i] = index.Item(i).Point
+			}
+
+			img := renderVoronoi(index, 110, 90, 0, color.RGBA{}, 3)
+			for y := 0; y < 90; y++ {
+				for x := 0; x < 110; x++ {
+					q := Point{float64(x), float64(y)}
+					want := bruteForce(pts, q, m)[0]
+					ok := false
+					for i := range pts {
+						if m.Distance(pts[i], q) == want && img.RGBAAt(x, y) == index.Item(i).Value {
+							ok = true
+						}
+					}
+					if !ok {
+						t.Fatalf("pixel (%d,%d) = %v is not the color of a nearest point", x, y, img.RGBAAt(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRenderVoronoi(b *testing.B) {
+	index := randomColorIndex(rand.New(rand.NewSource(1)), 100, Euclidean)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		renderVoronoi(index, 512, 512, 0, color.RGBA{}, 4)
+	}
+}
+
+func BenchmarkRenderVoronoi_PerPixel(b *testing.B) {
+	index := randomColorIndex(rand.New(rand.NewSource(1)), 100, Euclidean)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		voronoiPerPixel(index, 512, 512)
+	}
+}
+
+func TestVoronoiImage(t *testing.T) {
+	points := []mgl64.Vec2{{10, 10}, {30, 10}}
+	colors := []color.Color{color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	white := color.RGBA{255, 255, 255, 255}
+	green := color.RGBA{0, 255, 0, 255}
+	img, err := VoronoiImage(points, 40, 20, VoronoiOptions{
+		Color:       func(i int) color.Color { return colors[i] },
+		Border:      2,
+		BorderColor: white,
+		Marker:      MarkerCross,
+		MarkerSize:  2,
+		MarkerColor: green,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{0, 0, colors[0].(color.RGBA)},
+		{39, 19, colors[1].(color.RGBA)},
+		{18, 5, colors[0].(color.RGBA)},
+		{19, 5, white},
+		{20, 5, white},
+		{21, 5, white},
+		{22, 5, colors[1].(color.RGBA)},
+		{10, 10, green},
+		{12, 10, green},
+		{10, 8, green},
+		{11, 11, colors[0].(color.RGBA)},
+		{13, 10, colors[0].(color.RGBA)},
+	}
+	for _, tt := range tests {
+		if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("pixel (%d,%d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestVoronoiImage_Errors(t *testing.T) {
+	points := []mgl64.Vec2{{1, 1}, {5, 5}}
+	tests := []struct {
+		name   string
+		points []mgl64.Vec2
+		w, h   int
+		opts   VoronoiOptions
+	}{
+		{"no points", nil, 10, 10, VoronoiOptions{}},
+		{"zero width", points, 0, 10, VoronoiOptions{}},
+		{"negative height", points, 10, -1, VoronoiOptions{}},
+		{"nil color", points, 10, 10, VoronoiOptions{Color: func(int) color.Color { return nil }}},
+		{"bad marker", points, 10, 10, VoronoiOptions{Marker: MarkerNone + 1}},
+		{"negative marker size", points, 10, 10, VoronoiOptions{MarkerSize: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := VoronoiImage(tt.points, tt.w, tt.h, tt.opts); err == nil {
+				t.Error("no error")
+			}
+			if err := WriteVoronoi(&bytes.Buffer{}, SVG, tt.points, tt.w, tt.h, tt.opts); err == nil {
+				t.Error("no error writing")
+			}
+		})
+	}
+	if err := WriteVoronoi(&bytes.Buffer{}, SVG+1, points, 10, 10, VoronoiOptions{}); err == nil {
+		t.Error("no error for unknown format")
+	}
+}
+
+func TestVoronoi_NoFileOnError(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "vor.png")
+	if err := Voronoi(nil, 10, 10, Euclidean, filename); err == nil {
+		t.Fatal("no error for no points")
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Voronoi() left a file after an error: %v", err)
+	}
+
+	if err := Voronoi([]mgl64.Vec2{{1, 1}, {5, 5}}, 10, 10, Euclidean, filename); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		t.Errorf("Voronoi() wrote a bad PNG: %v", err)
+	}
+}
+
+func TestWriteVoronoi(t *testing.T) {
+	points := []mgl64.Vec2{{2, 2}, {12, 8}, {5, 13}}
+	opts := VoronoiOptions{Marker: MarkerCircle, MarkerSize: 1}
+
+	var b bytes.Buffer
+	if err := WriteVoronoi(&b, PNG, points, 16, 16, opts); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := png.Decode(&b); err != nil || img.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Errorf("PNG decodes to %v, %v", img, err)
+	}
+
+	b.Reset()
+	if err := WriteVoronoi(&b, JPEG, points, 16, 16, opts); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := jpeg.Decode(&b); err != nil || img.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Errorf("JPEG decodes to %v, %v", img, err)
+	}
+
+	b.Reset()
+	if err := WriteVoronoi(&b, SVG, points, 16, 16, opts); err != nil {
+		t.Fatal(err)
+	}
+	svg := b.String()
+	if !strings.HasPrefix(svg, "<svg") || strings.Count(svg, "<circle") != len(points) || !strings.Contains(svg, "data:image/png;base64,") {
+		t.Errorf("bad SVG:\n%s", svg)
+	}
+}